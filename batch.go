@@ -0,0 +1,49 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance
+
+import (
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+)
+
+// BatchPicker is implemented by balancers picking several instances at once cheaper than one by one.
+type BatchPicker interface {
+	// PickN returns the instances of the next n picks of e, or nil if there is no instance to pick.
+	PickN(e discovery.Result, n int) []discovery.Instance
+}
+
+// PickN picks n instances of e with lb in one call, for clients pipelining or batching their requests.
+// It uses the PickN method of lb if it implements BatchPicker, otherwise it calls Pick n times and
+// stops at the first pick returning no instance.
+//
+// Unlike the other helpers of this package, PickN does not look for a BatchPicker in the balancers
+// wrapped by lb, since calling it would skip the metrics and listeners of the wrappers.
+func PickN(lb loadbalance.Loadbalancer, e discovery.Result, n int) []discovery.Instance {
+	if bp, ok := lb.(BatchPicker); ok {
+		return bp.PickN(e, n)
+	}
+	var picked []discovery.Instance
+	for i := 0; i < n; i++ {
+		ins := lb.Pick(e)
+		if ins == nil {
+			break
+		}
+		picked = append(picked, ins)
+	}
+	return picked
+}
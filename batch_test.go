@@ -0,0 +1,62 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance_test
+
+import (
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+	roundrobin "github.com/hertz-contrib/loadbalance/round_robin"
+)
+
+func TestPickN(t *testing.T) {
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 10, nil),
+		},
+		CacheKey: "a",
+	}
+
+	// picked one by one
+	picked := loadbalanceEx.PickN(firstBalancer{}, e, 3)
+	assert.DeepEqual(t, 3, len(picked))
+	for _, ins := range picked {
+		assert.DeepEqual(t, "127.0.0.1:8880", ins.Address().String())
+	}
+	assert.DeepEqual(t, 0, len(loadbalanceEx.PickN(nilBalancer{}, e, 3)))
+
+	// picked by the balancer
+	lb := roundrobin.NewRoundRobinBalancer()
+	picked = loadbalanceEx.PickN(lb, e, 3)
+	assert.DeepEqual(t, 3, len(picked))
+	assert.DeepEqual(t, "127.0.0.1:8880", picked[0].Address().String())
+	assert.DeepEqual(t, "127.0.0.1:8881", picked[1].Address().String())
+	assert.DeepEqual(t, "127.0.0.1:8880", picked[2].Address().String())
+	// the batch took three turns
+	assert.DeepEqual(t, "127.0.0.1:8881", lb.Pick(e).Address().String())
+	assert.Nil(t, loadbalanceEx.PickN(lb, discovery.Result{CacheKey: "b"}, 3))
+
+	// the wrappers of a balancer are not bypassed
+	n := loadbalanceEx.NewNotifier(lb)
+	picks := 0
+	n.AddListener(loadbalanceEx.SynthesizedListener{PickFunc: func(loadbalanceEx.PickEvent) { picks++ }})
+	assert.DeepEqual(t, 2, len(loadbalanceEx.PickN(n, e, 2)))
+	assert.DeepEqual(t, 2, picks)
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance
+
+import (
+	"fmt"
+
+	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+)
+
+// Decorator wraps a Loadbalancer, like the NewBalancer functions of the metrics packages.
+type Decorator func(lb loadbalance.Loadbalancer) loadbalance.Loadbalancer
+
+// Builder assembles an algorithm, listeners and metrics into one Loadbalancer:
+//
+//	lb, err := loadbalance.NewBuilder().
+//		WithAlgorithm("round_robin").
+//		WithListener(ft).
+//		WithMetrics(func(lb loadbalance.Loadbalancer) loadbalance.Loadbalancer {
+//			return prometheus.NewBalancer(lb)
+//		}).
+//		Build()
+type Builder struct {
+	algorithm    string
+	listeners    []Listener
+	notifierOpts []NotifierOption
+	metrics      []Decorator
+}
+
+// NewBuilder creates a Builder of a round-robin balancer. The round-robin algorithm is registered by its
+// package, which must be imported, e.g. `import _ "github.com/hertz-contrib/loadbalance/round_robin"`,
+// otherwise Build fails unless another algorithm is set with WithAlgorithm.
+func NewBuilder() *Builder {
+	return &Builder{algorithm: "round_robin"}
+}
+
+// WithAlgorithm sets the name the algorithm is registered under, see Register.
+func (b *Builder) WithAlgorithm(name string) *Builder {
+	b.algorithm = name
+	return b
+}
+
+// WithListener registers l on a Notifier wrapping the algorithm.
+func (b *Builder) WithListener(l Listener) *Builder {
+	b.listeners = append(b.listeners, l)
+	return b
+}
+
+// WithNotifierOptions sets the options of the Notifier wrapping the algorithm.
+func (b *Builder) WithNotifierOptions(opts ...NotifierOption) *Builder {
+	b.notifierOpts = append(b.notifierOpts, opts...)
+	return b
+}
+
+// WithMetrics wraps the balancer with decorators, the first one is the innermost.
+func (b *Builder) WithMetrics(decorators ...Decorator) *Builder {
+	b.metrics = append(b.metrics, decorators...)
+	return b
+}
+
+// Build assembles the Loadbalancer, the algorithm is wrapped by the Notifier if any,
+// which is wrapped by the metrics decorators.
+func (b *Builder) Build() (loadbalance.Loadbalancer, error) {
+	build, ok := Get(b.algorithm)
+	if !ok {
+		return nil, fmt.Errorf("loadbalance: unknown algorithm %q (forgotten import?)", b.algorithm)
+	}
+	lb := build()
+	if len(b.listeners) > 0 || len(b.notifierOpts) > 0 {
+		n := NewNotifier(lb, b.notifierOpts...)
+		for _, l := range b.listeners {
+			n.AddListener(l)
+		}
+		lb = n
+	}
+	for _, d := range b.metrics {
+		lb = d(lb)
+	}
+	return lb, nil
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance_test
+
+import (
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+	_ "github.com/hertz-contrib/loadbalance/round_robin"
+)
+
+func TestBuilder(t *testing.T) {
+	_, err := loadbalanceEx.NewBuilder().WithAlgorithm("random").Build()
+	assert.NotNil(t, err)
+
+	lb, err := loadbalanceEx.NewBuilder().Build()
+	assert.Nil(t, err)
+	assert.DeepEqual(t, "round_robin", lb.Name())
+
+	ft := loadbalanceEx.NewFairnessTracker()
+	var order []string
+	decorator := func(name string) loadbalanceEx.Decorator {
+		return func(lb loadbalance.Loadbalancer) loadbalance.Loadbalancer {
+			order = append(order, name)
+			return wrapped{lb}
+		}
+	}
+	lb, err = loadbalanceEx.NewBuilder().
+		WithAlgorithm("round_robin").
+		WithListener(ft).
+		WithNotifierOptions(loadbalanceEx.WithPickTracing(1, 10)).
+		WithMetrics(decorator("inner"), decorator("outer")).
+		Build()
+	assert.Nil(t, err)
+	assert.DeepEqual(t, []string{"inner", "outer"}, order)
+
+	e := discovery.Result{
+		Instances: []discovery.Instance{discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil)},
+		CacheKey:  "a",
+	}
+	lb.Pick(e)
+	r, ok := ft.Report("a")
+	assert.True(t, ok)
+	assert.DeepEqual(t, uint64(1), r.Picks)
+	assert.DeepEqual(t, 1, len(loadbalanceEx.Traces(lb)))
+	_, ok = loadbalanceEx.Snapshot(lb, "a")
+	assert.True(t, ok)
+}
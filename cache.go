@@ -0,0 +1,51 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance
+
+import "github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+
+// CacheStats are the statistics of the per cache key state held by a balancer.
+type CacheStats struct {
+	// Hits is the number of picks which found the state of their cache key.
+	Hits uint64 `json:"hits"`
+	// Misses is the number of picks which did not find the state of their cache key.
+	Misses uint64 `json:"misses"`
+	// Computes is the number of states computed on a miss, concurrent misses share one computation.
+	Computes uint64 `json:"computes"`
+	// Evictions is the number of states evicted for being idle or above the bound of entries.
+	Evictions uint64 `json:"evictions"`
+	// Entries is the number of cache keys held.
+	Entries int `json:"entries"`
+}
+
+// CacheStatsProvider is implemented by balancers caching a state per cache key.
+type CacheStatsProvider interface {
+	CacheStats() CacheStats
+}
+
+// CacheStatsOf returns the cache statistics of lb, or of the balancer it wraps.
+// It returns false if no balancer in the chain implements CacheStatsProvider.
+func CacheStatsOf(lb loadbalance.Loadbalancer) (CacheStats, bool) {
+	csp, ok := find(lb, func(lb loadbalance.Loadbalancer) bool {
+		_, ok := lb.(CacheStatsProvider)
+		return ok
+	}).(CacheStatsProvider)
+	if !ok {
+		return CacheStats{}, false
+	}
+	return csp.CacheStats(), true
+}
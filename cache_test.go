@@ -0,0 +1,38 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance_test
+
+import (
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+	roundrobin "github.com/hertz-contrib/loadbalance/round_robin"
+)
+
+func TestCacheStatsOf(t *testing.T) {
+	_, ok := loadbalanceEx.CacheStatsOf(firstBalancer{})
+	assert.False(t, ok)
+
+	lb := roundrobin.NewRoundRobinBalancer()
+	lb.Pick(discovery.Result{CacheKey: "a"})
+	stats, ok := loadbalanceEx.CacheStatsOf(wrapped{lb})
+	assert.True(t, ok)
+	assert.DeepEqual(t, uint64(1), stats.Misses)
+	assert.DeepEqual(t, 1, stats.Entries)
+}
@@ -0,0 +1,56 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package loadbalance
+
+import (
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+)
+
+// Canary is a Loadbalancer splitting the picks between the groups of instances sharing the value of a version
+// tag, e.g. 95% to v1 and 5% to v2, to control canary releases from the client. Within a group, the instance is
+// picked by the wrapped balancer, which holds the state of every group under the cache key of the result suffixed
+// with "#" and the version, and the state of the whole result under its cache key.
+type Canary struct {
+	splitter
+}
+
+// NewCanary creates a Canary splitting the picks of lb between the versions read from the tag of the
+// instances, in proportion to weights. Instances whose version has no positive weight are not picked,
+// unless no weighted version has instances, in which case lb picks among all the instances.
+func NewCanary(lb loadbalance.Loadbalancer, tag string, weights map[string]int) *Canary {
+	c := &Canary{splitter{lb: lb, excluded: "version has no weight", key: func(ins discovery.Instance) (string, bool) {
+		version, _ := ins.Tag(tag)
+		return version, true
+	}}}
+	c.SetWeights(weights)
+	return c
+}
+
+// SetWeights replaces the weights of the versions, to shift traffic during a release.
+func (c *Canary) SetWeights(weights map[string]int) {
+	c.setWeights(weights)
+}
+
+// Explain implements the Explainer interface.
+func (c *Canary) Explain(e discovery.Result) Decision {
+	return c.explain(c.Name(), e)
+}
+
+// Name implements the Loadbalancer interface.
+func (c *Canary) Name() string {
+	return "canary"
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package loadbalance_test
+
+import (
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+	roundrobin "github.com/hertz-contrib/loadbalance/round_robin"
+)
+
+func TestCanary(t *testing.T) {
+	version := func(v string) map[string]string {
+		return map[string]string{"version": v}
+	}
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, version("v1")),
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 10, version("v1")),
+			discovery.NewInstance("tcp", "127.0.0.1:8882", 10, version("v2")),
+			discovery.NewInstance("tcp", "127.0.0.1:8883", 10, nil),
+		},
+		CacheKey: "a",
+	}
+	c := loadbalanceEx.NewCanary(roundrobin.NewRoundRobinBalancer(), "version", map[string]int{"v1": 95, "v2": 5})
+	c.Rebalance(e)
+	picks := func(n int) map[string]int {
+		res := make(map[string]int)
+		for i := 0; i < n; i++ {
+			res[c.Pick(e).Address().String()]++
+		}
+		return res
+	}
+	res := picks(1000)
+	assert.DeepEqual(t, 3, len(res))
+	assert.Assert(t, res["127.0.0.1:8882"] >= 48 && res["127.0.0.1:8882"] <= 52, res)
+	// round-robin within the group
+	assert.Assert(t, res["127.0.0.1:8880"]-res["127.0.0.1:8881"] <= 1 && res["127.0.0.1:8881"]-res["127.0.0.1:8880"] <= 1, res)
+
+	state, ok := loadbalanceEx.Snapshot(c, "a#v2")
+	assert.True(t, ok)
+	assert.DeepEqual(t, "127.0.0.1:8882", state.Instances[0].Address)
+
+	// shifting all the traffic
+	c.SetWeights(map[string]int{"v2": 1})
+	assert.DeepEqual(t, map[string]int{"127.0.0.1:8882": 10}, picks(10))
+
+	// the instances of v2 are gone, so is their group
+	e.Instances = e.Instances[:2]
+	c.Rebalance(e)
+	_, ok = loadbalanceEx.Snapshot(c, "a#v2")
+	assert.False(t, ok)
+	// no weighted version has instances, all the instances are picked
+	assert.DeepEqual(t, map[string]int{"127.0.0.1:8880": 5, "127.0.0.1:8881": 5}, picks(10))
+
+	c.Delete("a")
+	_, ok = loadbalanceEx.Snapshot(c, "a#v1")
+	assert.False(t, ok)
+	_, ok = loadbalanceEx.Snapshot(c, "a")
+	assert.False(t, ok)
+	assert.DeepEqual(t, "canary", c.Name())
+}
@@ -0,0 +1,268 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package config builds a load balancer stack, the algorithm and its metrics,
+// from a YAML or JSON document, so that it can be managed by configuration files.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+	"github.com/hertz-contrib/loadbalance/prometheus"
+	roundrobin "github.com/hertz-contrib/loadbalance/round_robin"
+	"github.com/hertz-contrib/loadbalance/statsd"
+	"gopkg.in/yaml.v3"
+)
+
+// Config describes a load balancer stack.
+type Config struct {
+	// Algorithm is the name of the algorithm, "round_robin" by default.
+	// Other algorithms are the ones registered with loadbalance.Register.
+	Algorithm  string            `json:"algorithm,omitempty" yaml:"algorithm,omitempty"`
+	RoundRobin roundrobin.Config `json:"round_robin,omitempty" yaml:"round_robin,omitempty"`
+	// Fallback are the algorithms asked in order when the algorithm picks no instance.
+	Fallback []string `json:"fallback,omitempty" yaml:"fallback,omitempty"`
+	// Prometheus exports the metrics of the balancer to prometheus if set.
+	Prometheus *prometheus.Config `json:"prometheus,omitempty" yaml:"prometheus,omitempty"`
+	// StatsD sends the metrics of the balancer to a StatsD server if set.
+	StatsD *statsd.Config `json:"statsd,omitempty" yaml:"statsd,omitempty"`
+}
+
+// Load parses a YAML or JSON document into a Config and validates it.
+// Unknown fields are rejected, and durations are written like "10s".
+func Load(data []byte) (Config, error) {
+	var cfg Config
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, fmt.Errorf("config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// LoadFile is the same as Load with the content of the file at path.
+func LoadFile(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	return Load(data)
+}
+
+// Validate returns an error if the configuration is invalid.
+func (c Config) Validate() error {
+	switch c.Algorithm {
+	case "", "round_robin":
+		if err := c.RoundRobin.Validate(); err != nil {
+			return err
+		}
+	default:
+		if _, ok := loadbalanceEx.Get(c.Algorithm); !ok {
+			return fmt.Errorf("config: unknown algorithm %q", c.Algorithm)
+		}
+	}
+	for _, name := range c.Fallback {
+		if _, ok := loadbalanceEx.Get(name); !ok {
+			return fmt.Errorf("config: unknown fallback algorithm %q", name)
+		}
+	}
+	if c.Prometheus != nil {
+		if err := c.Prometheus.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.StatsD != nil {
+		if err := c.StatsD.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stack is the balancers built from a Config.
+type stack struct {
+	cfg    Config
+	lb     loadbalance.Loadbalancer
+	closer io.Closer
+}
+
+func newStack(cfg Config) (*stack, error) {
+	var lb loadbalance.Loadbalancer
+	switch cfg.Algorithm {
+	case "", "round_robin":
+		lb = roundrobin.NewRoundRobinBalancer(cfg.RoundRobin.Options()...)
+	default:
+		lb = loadbalanceEx.MustGet(cfg.Algorithm)()
+	}
+	if len(cfg.Fallback) > 0 {
+		fallbacks := make([]loadbalance.Loadbalancer, 0, len(cfg.Fallback))
+		for _, name := range cfg.Fallback {
+			fallbacks = append(fallbacks, loadbalanceEx.MustGet(name)())
+		}
+		lb = loadbalanceEx.NewFallback(lb, fallbacks...)
+	}
+	if cfg.Prometheus != nil {
+		lb = prometheus.NewBalancer(lb, cfg.Prometheus.Options()...)
+	}
+	s := &stack{cfg: cfg}
+	if cfg.StatsD != nil {
+		sb, err := statsd.NewBalancer(lb, cfg.StatsD.Options()...)
+		if err != nil {
+			return nil, err
+		}
+		lb, s.closer = sb, sb
+	}
+	s.lb = lb
+	return s, nil
+}
+
+func (s *stack) close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
+
+// WithDefaults returns the configuration with the default value of its zero fields,
+// the sections of the disabled metrics stay nil.
+func (c Config) WithDefaults() Config {
+	if c.Algorithm == "" {
+		c.Algorithm = "round_robin"
+	}
+	if c.Algorithm == "round_robin" {
+		c.RoundRobin = c.RoundRobin.WithDefaults()
+	}
+	if c.Prometheus != nil {
+		p := c.Prometheus.WithDefaults()
+		c.Prometheus = &p
+	}
+	if c.StatsD != nil {
+		s := c.StatsD.WithDefaults()
+		c.StatsD = &s
+	}
+	return c
+}
+
+// Balancer is a load balancer stack built from a Config, which can be replaced at runtime with ApplyConfig.
+type Balancer struct {
+	stack atomic.Value // *stack
+
+	// mu serializes ApplyConfig with the updates of the instances
+	mu      sync.RWMutex
+	results sync.Map // cache key -> discovery.Result
+}
+
+// New validates cfg and builds the load balancer stack it describes.
+// Close must be called to release the resources of the stack.
+func New(cfg Config) (*Balancer, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	s, err := newStack(cfg)
+	if err != nil {
+		return nil, err
+	}
+	b := &Balancer{}
+	b.stack.Store(s)
+	return b, nil
+}
+
+// ApplyConfig validates cfg and atomically replaces the stack with the one cfg describes,
+// even if it uses another algorithm. The instances of every cache key are handed over
+// to the new stack before it serves picks, then the previous stack is closed.
+// The position of the algorithm within the instances is not carried over.
+func (b *Balancer) ApplyConfig(cfg Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	s, err := newStack(cfg)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.results.Range(func(key, value interface{}) bool {
+		s.lb.Rebalance(value.(discovery.Result))
+		return true
+	})
+	old := b.stack.Load().(*stack)
+	b.stack.Store(s)
+	return old.close()
+}
+
+// Describe returns the configuration of the current stack with the default value of its zero fields,
+// so that operators can check what a running process uses.
+func (b *Balancer) Describe() Config {
+	return b.stack.Load().(*stack).cfg.WithDefaults()
+}
+
+func (b *Balancer) lb() loadbalance.Loadbalancer {
+	return b.stack.Load().(*stack).lb
+}
+
+// Pick implements the Loadbalancer interface.
+func (b *Balancer) Pick(e discovery.Result) discovery.Instance {
+	// the algorithms cache the instances of the first pick of a cache key
+	if _, ok := b.results.Load(e.CacheKey); !ok {
+		b.mu.RLock()
+		b.results.LoadOrStore(e.CacheKey, e)
+		b.mu.RUnlock()
+	}
+	return b.lb().Pick(e)
+}
+
+// Rebalance implements the Loadbalancer interface.
+func (b *Balancer) Rebalance(e discovery.Result) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	b.results.Store(e.CacheKey, e)
+	b.lb().Rebalance(e)
+}
+
+// Delete implements the Loadbalancer interface.
+func (b *Balancer) Delete(cacheKey string) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	b.results.Delete(cacheKey)
+	b.lb().Delete(cacheKey)
+}
+
+// Name implements the Loadbalancer interface.
+func (b *Balancer) Name() string {
+	return b.lb().Name()
+}
+
+// Unwrap returns the outermost balancer of the current stack.
+func (b *Balancer) Unwrap() loadbalance.Loadbalancer {
+	return b.lb()
+}
+
+// Close releases the resources of the current stack.
+func (b *Balancer) Close() error {
+	return b.stack.Load().(*stack).close()
+}
@@ -0,0 +1,229 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+	"github.com/hertz-contrib/loadbalance/prometheus"
+	roundrobin "github.com/hertz-contrib/loadbalance/round_robin"
+	"github.com/hertz-contrib/loadbalance/statsd"
+)
+
+func TestLoad(t *testing.T) {
+	cfg, err := Load([]byte(`
+algorithm: round_robin
+round_robin:
+  name: rr
+statsd:
+  address: 127.0.0.1:8125
+  flush_interval: 10s
+`))
+	assert.Nil(t, err)
+	assert.DeepEqual(t, "rr", cfg.RoundRobin.Name)
+	assert.Assert(t, cfg.Prometheus == nil)
+	assert.DeepEqual(t, 10*time.Second, cfg.StatsD.FlushInterval)
+
+	// JSON is a subset of YAML
+	cfg, err = Load([]byte(`{"prometheus": {"namespace": "app", "buckets": [0.001, 0.01]}}`))
+	assert.Nil(t, err)
+	assert.DeepEqual(t, "app", cfg.Prometheus.Namespace)
+
+	cfg, err = Load(nil)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, Config{}, cfg)
+
+	_, err = Load([]byte(`algorithm: random`))
+	assert.NotNil(t, err)
+	_, err = Load([]byte(`fallback: [random]`))
+	assert.NotNil(t, err)
+	_, err = Load([]byte(`round_robbin: {}`))
+	assert.NotNil(t, err)
+	_, err = Load([]byte(`prometheus: {namespace: "my-app"}`))
+	assert.NotNil(t, err)
+
+	path := filepath.Join(t.TempDir(), "lb.yaml")
+	assert.Nil(t, os.WriteFile(path, []byte(`round_robin: {name: rr}`), 0o644))
+	cfg, err = LoadFile(path)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, "rr", cfg.RoundRobin.Name)
+}
+
+func TestNew(t *testing.T) {
+	cfg, err := Load([]byte(`
+round_robin:
+  name: rr
+prometheus: {}
+statsd:
+  address: 127.0.0.1:8125
+`))
+	assert.Nil(t, err)
+	b, err := New(cfg)
+	assert.Nil(t, err)
+	defer b.Close()
+	assert.DeepEqual(t, "rr", b.Name())
+
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+		},
+		CacheKey: "a",
+	}
+	b.Rebalance(e)
+	assert.DeepEqual(t, "127.0.0.1:8880", b.Pick(e).Address().String())
+	// the state of the algorithm is reachable through the stack
+	state, ok := loadbalanceEx.Snapshot(b, "a")
+	assert.True(t, ok)
+	assert.DeepEqual(t, uint64(1), state.Instances[0].Picks)
+	b.Delete("a")
+	_, ok = loadbalanceEx.Snapshot(b, "a")
+	assert.False(t, ok)
+}
+
+func TestApplyConfig(t *testing.T) {
+	b, err := New(Config{})
+	assert.Nil(t, err)
+	defer b.Close()
+
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 10, nil),
+		},
+		CacheKey: "a",
+	}
+	// the first pick caches the instances without rebalance
+	b.Pick(e)
+	b.Rebalance(discovery.Result{Instances: e.Instances[:1], CacheKey: "b"})
+	b.Rebalance(discovery.Result{CacheKey: "c"})
+	b.Delete("c")
+
+	assert.NotNil(t, b.ApplyConfig(Config{Algorithm: "random"}))
+	assert.DeepEqual(t, "round_robin", b.Name())
+
+	assert.Nil(t, b.ApplyConfig(Config{RoundRobin: roundrobin.Config{Name: "rr"}}))
+	assert.DeepEqual(t, "rr", b.Name())
+	states := loadbalanceEx.States(b)
+	assert.DeepEqual(t, 2, len(states))
+	state, ok := loadbalanceEx.Snapshot(b, "a")
+	assert.True(t, ok)
+	assert.DeepEqual(t, 2, len(state.Instances))
+	state, ok = loadbalanceEx.Snapshot(b, "b")
+	assert.True(t, ok)
+	assert.DeepEqual(t, 1, len(state.Instances))
+}
+
+func TestOverrideFromEnv(t *testing.T) {
+	rate := 0.5
+	base := Config{StatsD: &statsd.Config{Address: "127.0.0.1:8125", TimingSampleRate: &rate}}
+	cfg, err := OverrideFromEnv(base)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, base, cfg)
+
+	t.Setenv("HERTZ_LB_ROUND_ROBIN_NAME", "rr")
+	t.Setenv("HERTZ_LB_ROUND_ROBIN_MAX_IDLE", "10m")
+	t.Setenv("HERTZ_LB_ROUND_ROBIN_MAX_ENTRIES", "100")
+	t.Setenv("HERTZ_LB_ROUND_ROBIN_MAX_AGE", "1m")
+	t.Setenv("HERTZ_LB_ROUND_ROBIN_DISABLE_SINGLEFLIGHT", "true")
+	t.Setenv("HERTZ_LB_PROMETHEUS_NAMESPACE", "app")
+	t.Setenv("HERTZ_LB_STATSD_FLUSH_INTERVAL", "5s")
+	t.Setenv("HERTZ_LB_STATSD_MAX_PACKET_SIZE", "512")
+	cfg, err = OverrideFromEnv(base)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, "rr", cfg.RoundRobin.Name)
+	assert.DeepEqual(t, 10*time.Minute, cfg.RoundRobin.MaxIdle)
+	assert.DeepEqual(t, 100, cfg.RoundRobin.MaxEntries)
+	assert.DeepEqual(t, time.Minute, cfg.RoundRobin.MaxAge)
+	assert.True(t, cfg.RoundRobin.DisableSingleflight)
+	assert.DeepEqual(t, "app", cfg.Prometheus.Namespace)
+	assert.DeepEqual(t, "127.0.0.1:8125", cfg.StatsD.Address)
+	assert.DeepEqual(t, 5*time.Second, cfg.StatsD.FlushInterval)
+	assert.DeepEqual(t, 512, cfg.StatsD.MaxPacketSize)
+	assert.DeepEqual(t, 0.5, *cfg.StatsD.TimingSampleRate)
+	// the configuration of the caller is left untouched
+	assert.DeepEqual(t, time.Duration(0), base.StatsD.FlushInterval)
+
+	t.Setenv("HERTZ_LB_STATSD_MAX_PACKET_SIZE", "big")
+	_, err = OverrideFromEnv(base)
+	assert.NotNil(t, err)
+
+	t.Setenv("HERTZ_LB_STATSD_MAX_PACKET_SIZE", "512")
+	t.Setenv("HERTZ_LB_ALGORITHM", "random")
+	_, err = OverrideFromEnv(base)
+	assert.NotNil(t, err)
+}
+
+type firstBalancer struct {
+	loadbalance.Loadbalancer
+}
+
+func (firstBalancer) Name() string { return "first" }
+
+func TestRegisteredAlgorithm(t *testing.T) {
+	loadbalanceEx.Register("config_test", func() loadbalance.Loadbalancer {
+		return firstBalancer{roundrobin.NewRoundRobinBalancer()}
+	})
+	cfg, err := Load([]byte(`algorithm: config_test`))
+	assert.Nil(t, err)
+	b, err := New(cfg)
+	assert.Nil(t, err)
+	defer b.Close()
+	assert.DeepEqual(t, "first", b.Name())
+}
+
+func TestDescribe(t *testing.T) {
+	b, err := New(Config{Prometheus: &prometheus.Config{Namespace: "app"}})
+	assert.Nil(t, err)
+	defer b.Close()
+
+	cfg := b.Describe()
+	assert.DeepEqual(t, "round_robin", cfg.Algorithm)
+	assert.DeepEqual(t, "round_robin", cfg.RoundRobin.Name)
+	assert.DeepEqual(t, "app", cfg.Prometheus.Namespace)
+	assert.Assert(t, len(cfg.Prometheus.Buckets) > 0)
+	assert.Assert(t, cfg.StatsD == nil)
+
+	assert.Nil(t, b.ApplyConfig(Config{StatsD: &statsd.Config{}}))
+	cfg = b.Describe()
+	assert.Assert(t, cfg.Prometheus == nil)
+	assert.DeepEqual(t, "127.0.0.1:8125", cfg.StatsD.Address)
+	assert.DeepEqual(t, 0.01, *cfg.StatsD.TimingSampleRate)
+}
+
+func TestFallback(t *testing.T) {
+	cfg, err := Load([]byte(`fallback: [round_robin]`))
+	assert.Nil(t, err)
+	b, err := New(cfg)
+	assert.Nil(t, err)
+	defer b.Close()
+	assert.DeepEqual(t, "fallback", b.Name())
+
+	e := discovery.Result{
+		Instances: []discovery.Instance{discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil)},
+		CacheKey:  "a",
+	}
+	// the primary caches the empty result of the rebalance, the fallback the instances of the pick
+	b.Unwrap().(*loadbalanceEx.Fallback).Unwrap().Rebalance(discovery.Result{CacheKey: "a"})
+	assert.DeepEqual(t, e.Instances[0], b.Pick(e))
+}
@@ -0,0 +1,151 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/hertz-contrib/loadbalance/prometheus"
+	"github.com/hertz-contrib/loadbalance/statsd"
+)
+
+// EnvPrefix is the prefix of the environment variables read by OverrideFromEnv.
+const EnvPrefix = "HERTZ_LB_"
+
+// OverrideFromEnv returns cfg with the settings found in the environment variables below,
+// then validates it. Setting a variable of the prometheus or statsd section enables it.
+//
+//	HERTZ_LB_ALGORITHM
+//	HERTZ_LB_ROUND_ROBIN_NAME
+//	HERTZ_LB_ROUND_ROBIN_MAX_IDLE, a duration like "10m"
+//	HERTZ_LB_ROUND_ROBIN_MAX_ENTRIES
+//	HERTZ_LB_ROUND_ROBIN_MAX_AGE, a duration like "1m"
+//	HERTZ_LB_ROUND_ROBIN_DISABLE_SINGLEFLIGHT, a boolean like "true"
+//	HERTZ_LB_PROMETHEUS_NAMESPACE
+//	HERTZ_LB_STATSD_ADDRESS
+//	HERTZ_LB_STATSD_PREFIX
+//	HERTZ_LB_STATSD_FLUSH_INTERVAL, a duration like "10s"
+//	HERTZ_LB_STATSD_TIMING_SAMPLE_RATE
+//	HERTZ_LB_STATSD_MAX_PACKET_SIZE
+func OverrideFromEnv(cfg Config) (Config, error) {
+	if v, ok := lookupEnv("ALGORITHM"); ok {
+		cfg.Algorithm = v
+	}
+	if v, ok := lookupEnv("ROUND_ROBIN_NAME"); ok {
+		cfg.RoundRobin.Name = v
+	}
+	if v, ok := lookupEnv("ROUND_ROBIN_MAX_IDLE"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("config: invalid %sROUND_ROBIN_MAX_IDLE: %w", EnvPrefix, err)
+		}
+		cfg.RoundRobin.MaxIdle = d
+	}
+	if v, ok := lookupEnv("ROUND_ROBIN_MAX_ENTRIES"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("config: invalid %sROUND_ROBIN_MAX_ENTRIES: %w", EnvPrefix, err)
+		}
+		cfg.RoundRobin.MaxEntries = n
+	}
+	if v, ok := lookupEnv("ROUND_ROBIN_MAX_AGE"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("config: invalid %sROUND_ROBIN_MAX_AGE: %w", EnvPrefix, err)
+		}
+		cfg.RoundRobin.MaxAge = d
+	}
+	if v, ok := lookupEnv("ROUND_ROBIN_DISABLE_SINGLEFLIGHT"); ok {
+		disabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("config: invalid %sROUND_ROBIN_DISABLE_SINGLEFLIGHT: %w", EnvPrefix, err)
+		}
+		cfg.RoundRobin.DisableSingleflight = disabled
+	}
+	if v, ok := lookupEnv("PROMETHEUS_NAMESPACE"); ok {
+		cfg.Prometheus = copyPrometheus(cfg.Prometheus)
+		cfg.Prometheus.Namespace = v
+	}
+
+	statsdEnv := map[string]func(v string) error{
+		"STATSD_ADDRESS": func(v string) error {
+			cfg.StatsD.Address = v
+			return nil
+		},
+		"STATSD_PREFIX": func(v string) error {
+			cfg.StatsD.Prefix = v
+			return nil
+		},
+		"STATSD_FLUSH_INTERVAL": func(v string) (err error) {
+			cfg.StatsD.FlushInterval, err = time.ParseDuration(v)
+			return err
+		},
+		"STATSD_TIMING_SAMPLE_RATE": func(v string) error {
+			rate, err := strconv.ParseFloat(v, 64)
+			cfg.StatsD.TimingSampleRate = &rate
+			return err
+		},
+		"STATSD_MAX_PACKET_SIZE": func(v string) (err error) {
+			cfg.StatsD.MaxPacketSize, err = strconv.Atoi(v)
+			return err
+		},
+	}
+	copied := false
+	for name, set := range statsdEnv {
+		v, ok := lookupEnv(name)
+		if !ok {
+			continue
+		}
+		if !copied {
+			cfg.StatsD, copied = copyStatsD(cfg.StatsD), true
+		}
+		if err := set(v); err != nil {
+			return Config{}, fmt.Errorf("config: invalid %s%s: %w", EnvPrefix, name, err)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func lookupEnv(name string) (string, bool) {
+	return os.LookupEnv(EnvPrefix + name)
+}
+
+// copyPrometheus returns a copy of c, or an empty configuration if c is nil,
+// so that overriding it does not modify the configuration of the caller.
+func copyPrometheus(c *prometheus.Config) *prometheus.Config {
+	if c == nil {
+		return &prometheus.Config{}
+	}
+	cp := *c
+	return &cp
+}
+
+// copyStatsD is the same as copyPrometheus for statsd.
+func copyStatsD(c *statsd.Config) *statsd.Config {
+	if c == nil {
+		return &statsd.Config{}
+	}
+	cp := *c
+	return &cp
+}
@@ -0,0 +1,151 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package debug provides an http.Handler rendering the live state of registered balancers,
+// similar to the /clusters admin page of Envoy.
+package debug
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+)
+
+var (
+	mu        sync.RWMutex
+	balancers = make(map[string]loadbalance.Loadbalancer)
+)
+
+// Balancer is the state of a registered balancer rendered by Handler.
+type Balancer struct {
+	Name   string                    `json:"name"`
+	States []loadbalanceEx.State     `json:"states"`
+	Traces []loadbalanceEx.PickTrace `json:"traces,omitempty"`
+	// Cache is nil if the balancer does not provide cache statistics.
+	Cache *loadbalanceEx.CacheStats `json:"cache,omitempty"`
+}
+
+// Register registers lb under name so that its state is rendered by Handler.
+// Registering another balancer under the same name replaces the previous one.
+func Register(name string, lb loadbalance.Loadbalancer) {
+	mu.Lock()
+	defer mu.Unlock()
+	balancers[name] = lb
+}
+
+// Unregister removes the balancer registered under name.
+func Unregister(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(balancers, name)
+}
+
+// Balancers returns the state of every registered balancer, sorted by name.
+func Balancers() []Balancer {
+	mu.RLock()
+	defer mu.RUnlock()
+	res := make([]Balancer, 0, len(balancers))
+	for name, lb := range balancers {
+		states := loadbalanceEx.States(lb)
+		sort.Slice(states, func(i, j int) bool {
+			return states[i].CacheKey < states[j].CacheKey
+		})
+		b := Balancer{Name: name, States: states, Traces: loadbalanceEx.Traces(lb)}
+		if stats, ok := loadbalanceEx.CacheStatsOf(lb); ok {
+			b.Cache = &stats
+		}
+		res = append(res, b)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
+// Handler returns an http.Handler rendering the state of the registered balancers.
+// The state is rendered as JSON if the request has "format=json" in its query
+// or accepts "application/json", and as an HTML page otherwise.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := Balancers()
+		if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			_ = json.NewEncoder(w).Encode(res)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = page.Execute(w, res)
+	})
+}
+
+// VerboseHandler returns an http.Handler switching the logging of every pick, see loadbalance.SetVerbose.
+// POST and PUT requests set it from the "enabled" query parameter, e.g. "?enabled=true".
+// Every request is answered with the current setting as JSON.
+func VerboseHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost || r.Method == http.MethodPut {
+			enabled, err := strconv.ParseBool(r.URL.Query().Get("enabled"))
+			if err != nil {
+				http.Error(w, "invalid enabled parameter: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			loadbalanceEx.SetVerbose(enabled)
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(map[string]bool{"verbose": loadbalanceEx.Verbose()})
+	})
+}
+
+var page = template.Must(template.New("balancers").Parse(`<!DOCTYPE html>
+<html>
+<head><title>loadbalance</title></head>
+<body>
+{{- range .}}
+<h2>{{.Name}}</h2>
+{{- with .Cache}}
+<p>cache: {{.Entries}} entries, {{.Hits}} hits, {{.Misses}} misses, {{.Computes}} computes, {{.Evictions}} evictions</p>
+{{- end}}
+{{- range .States}}
+<h3>{{.CacheKey}}</h3>
+<table border="1">
+<tr><th>address</th><th>weight</th><th>picks</th></tr>
+{{- range .Instances}}
+<tr><td>{{.Address}}</td><td>{{.Weight}}</td><td>{{.Picks}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Traces}}
+<h3>sampled picks</h3>
+<table border="1">
+<tr><th>time</th><th>cache key</th><th>candidates</th><th>chosen</th></tr>
+{{- range .Traces}}
+<tr><td>{{.Time.Format "2006-01-02 15:04:05.000"}}</td><td>{{.CacheKey}}</td><td>{{range .Candidates}}{{.Address}} ({{.Weight}}) {{end}}</td><td>{{.Chosen}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- else}}
+<p>no balancer registered</p>
+{{- end}}
+</body>
+</html>
+`))
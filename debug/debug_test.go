@@ -0,0 +1,95 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package debug
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+	roundrobin "github.com/hertz-contrib/loadbalance/round_robin"
+)
+
+func TestHandler(t *testing.T) {
+	handler := Handler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Assert(t, strings.Contains(rec.Body.String(), "no balancer registered"))
+
+	lb := loadbalanceEx.NewNotifier(roundrobin.NewRoundRobinBalancer(), loadbalanceEx.WithPickTracing(1, 10))
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 10, nil),
+		},
+		CacheKey: "svc",
+	}
+	lb.Rebalance(e)
+	lb.Pick(e)
+	Register("rr", lb)
+	defer Unregister("rr")
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.DeepEqual(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	body := rec.Body.String()
+	assert.Assert(t, strings.Contains(body, "<h2>rr</h2>"), body)
+	assert.Assert(t, strings.Contains(body, "<p>cache: 1 entries, 1 hits, 0 misses, 0 computes, 0 evictions</p>"), body)
+	assert.Assert(t, strings.Contains(body, "<tr><td>127.0.0.1:8880</td><td>10</td><td>1</td></tr>"), body)
+	assert.Assert(t, strings.Contains(body, "<td>svc</td><td>127.0.0.1:8880 (10) 127.0.0.1:8881 (10) </td><td>127.0.0.1:8880</td>"), body)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?format=json", nil))
+	var res []Balancer
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	assert.DeepEqual(t, 1, len(res))
+	assert.DeepEqual(t, "rr", res[0].Name)
+	assert.DeepEqual(t, "svc", res[0].States[0].CacheKey)
+	assert.DeepEqual(t, uint64(0), res[0].States[0].Instances[1].Picks)
+	assert.DeepEqual(t, "127.0.0.1:8880", res[0].Traces[0].Chosen)
+}
+
+func TestVerboseHandler(t *testing.T) {
+	handler := VerboseHandler()
+	defer loadbalanceEx.SetVerbose(false)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/?enabled=true", nil))
+	assert.DeepEqual(t, http.StatusOK, rec.Code)
+	assert.DeepEqual(t, "{\"verbose\":true}\n", rec.Body.String())
+	assert.True(t, loadbalanceEx.Verbose())
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/?enabled=maybe", nil))
+	assert.DeepEqual(t, http.StatusBadRequest, rec.Code)
+	assert.True(t, loadbalanceEx.Verbose())
+
+	// GET requests do not change the setting
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?enabled=false", nil))
+	assert.DeepEqual(t, "{\"verbose\":true}\n", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/?enabled=false", nil))
+	assert.False(t, loadbalanceEx.Verbose())
+}
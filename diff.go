@@ -0,0 +1,95 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance
+
+import (
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+)
+
+// WeightChange is the weight change of an instance kept by a rebalance.
+type WeightChange struct {
+	Address   string `json:"address"`
+	OldWeight int    `json:"old_weight"`
+	NewWeight int    `json:"new_weight"`
+}
+
+// Diff is the difference between the instances of two successive rebalances of a cache key.
+type Diff struct {
+	CacheKey string          `json:"cache_key"`
+	Time     time.Time       `json:"time"`
+	Added    []InstanceState `json:"added,omitempty"`
+	Removed  []InstanceState `json:"removed,omitempty"`
+	Changed  []WeightChange  `json:"changed,omitempty"`
+}
+
+// Empty reports whether the rebalance changed nothing.
+func (d Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// instanceSet is the address and weight of instances, in the order of the discovery result.
+// The instances with a malformed address are left out.
+type instanceSet struct {
+	addrs   []string
+	weights map[string]int
+}
+
+func newInstanceSet(instances []discovery.Instance) *instanceSet {
+	s := &instanceSet{
+		addrs:   make([]string, 0, len(instances)),
+		weights: make(map[string]int, len(instances)),
+	}
+	for _, ins := range instances {
+		addr, ok := validAddress(ins)
+		if !ok {
+			continue
+		}
+		if _, ok := s.weights[addr]; !ok {
+			s.addrs = append(s.addrs, addr)
+		}
+		s.weights[addr] = ins.Weight()
+	}
+	return s
+}
+
+// NewDiff returns the difference between the old and the current instances of cacheKey,
+// for balancers emitting their own rebalance events.
+func NewDiff(cacheKey string, old, cur []discovery.Instance) Diff {
+	return diff(cacheKey, newInstanceSet(old), newInstanceSet(cur))
+}
+
+func diff(cacheKey string, old, cur *instanceSet) Diff {
+	d := Diff{CacheKey: cacheKey, Time: time.Now()}
+	for _, addr := range cur.addrs {
+		weight := cur.weights[addr]
+		oldWeight, ok := old.weights[addr]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, InstanceState{Address: addr, Weight: weight})
+		case oldWeight != weight:
+			d.Changed = append(d.Changed, WeightChange{Address: addr, OldWeight: oldWeight, NewWeight: weight})
+		}
+	}
+	for _, addr := range old.addrs {
+		if _, ok := cur.weights[addr]; !ok {
+			d.Removed = append(d.Removed, InstanceState{Address: addr, Weight: old.weights[addr]})
+		}
+	}
+	return d
+}
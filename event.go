@@ -0,0 +1,257 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+)
+
+// PickEvent is emitted when a balancer is asked to pick an instance.
+type PickEvent struct {
+	Balancer string
+	Result   discovery.Result
+	// Instance is the picked instance, nil if the balancer returned none.
+	Instance discovery.Instance
+	// Failure is why the balancer returned no instance, one of the Failure constants telling
+	// an empty result from instances with invalid weights, empty if an instance was picked.
+	Failure string
+}
+
+// RebalanceEvent is emitted when a balancer replaces the state of a cache key.
+type RebalanceEvent struct {
+	Balancer string
+	Result   discovery.Result
+	// Diff is the difference with the previous rebalance of the cache key.
+	Diff Diff
+	// Malformed are the malformed addresses of the instances, see SanitizeInstances.
+	Malformed []string
+}
+
+// DeleteEvent is emitted when a balancer deletes the state of a cache key.
+type DeleteEvent struct {
+	Balancer string
+	CacheKey string
+}
+
+// Listener receives the events emitted by a Notifier.
+// Its methods are called synchronously on the path of the balancer and must not block.
+type Listener interface {
+	OnPick(e PickEvent)
+	OnRebalance(e RebalanceEvent)
+	OnDelete(e DeleteEvent)
+}
+
+// SynthesizedListener synthesizes a Listener using functions, nil functions are skipped.
+type SynthesizedListener struct {
+	PickFunc      func(e PickEvent)
+	RebalanceFunc func(e RebalanceEvent)
+	DeleteFunc    func(e DeleteEvent)
+}
+
+// OnPick implements the Listener interface.
+func (sl SynthesizedListener) OnPick(e PickEvent) {
+	if sl.PickFunc != nil {
+		sl.PickFunc(e)
+	}
+}
+
+// OnRebalance implements the Listener interface.
+func (sl SynthesizedListener) OnRebalance(e RebalanceEvent) {
+	if sl.RebalanceFunc != nil {
+		sl.RebalanceFunc(e)
+	}
+}
+
+// OnDelete implements the Listener interface.
+func (sl SynthesizedListener) OnDelete(e DeleteEvent) {
+	if sl.DeleteFunc != nil {
+		sl.DeleteFunc(e)
+	}
+}
+
+type sampledListener struct {
+	Listener
+	rate  uint32
+	picks uint32
+}
+
+// Sampled returns a Listener forwarding one pick event out of rate to l.
+// Rebalance and delete events are always forwarded.
+func Sampled(l Listener, rate uint32) Listener {
+	if rate <= 1 {
+		return l
+	}
+	return &sampledListener{Listener: l, rate: rate}
+}
+
+// OnPick implements the Listener interface.
+func (sl *sampledListener) OnPick(e PickEvent) {
+	if atomic.AddUint32(&sl.picks, 1)%sl.rate == 0 {
+		sl.Listener.OnPick(e)
+	}
+}
+
+// NotifierOption configures a Notifier.
+type NotifierOption func(n *Notifier)
+
+// WithDiffHistory makes the Notifier retain the last size non-empty rebalance diffs, see Diffs.
+func WithDiffHistory(size int) NotifierOption {
+	return func(n *Notifier) {
+		n.historySize = size
+	}
+}
+
+// Notifier wraps a Loadbalancer and emits its events to the registered listeners.
+type Notifier struct {
+	lb loadbalance.Loadbalancer
+
+	mu        sync.Mutex
+	listeners atomic.Value // []*Listener
+
+	rebalanceMu sync.Mutex
+	instances   map[string]*instanceSet
+	historySize int
+	history     []Diff
+
+	traceRate  uint32
+	traceSize  int
+	tracePicks uint32
+	tracesMu   sync.Mutex
+	traces     []PickTrace
+}
+
+// NewNotifier creates a Notifier wrapping lb.
+func NewNotifier(lb loadbalance.Loadbalancer, opts ...NotifierOption) *Notifier {
+	n := &Notifier{
+		lb:        lb,
+		instances: make(map[string]*instanceSet),
+	}
+	n.listeners.Store([]*Listener(nil))
+	for _, opt := range opts {
+		opt(n)
+	}
+	return n
+}
+
+// Diffs returns the retained rebalance diffs, oldest first.
+func (n *Notifier) Diffs() []Diff {
+	n.rebalanceMu.Lock()
+	defer n.rebalanceMu.Unlock()
+	return append([]Diff(nil), n.history...)
+}
+
+// AddListener registers l and returns a function unregistering it.
+func (n *Notifier) AddListener(l Listener) (remove func()) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	ref := &l
+	old := n.listeners.Load().([]*Listener)
+	listeners := make([]*Listener, len(old), len(old)+1)
+	copy(listeners, old)
+	n.listeners.Store(append(listeners, ref))
+	return func() {
+		n.removeListener(ref)
+	}
+}
+
+func (n *Notifier) removeListener(ref *Listener) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	old := n.listeners.Load().([]*Listener)
+	listeners := make([]*Listener, 0, len(old))
+	for _, l := range old {
+		if l != ref {
+			listeners = append(listeners, l)
+		}
+	}
+	n.listeners.Store(listeners)
+}
+
+// Pick implements the Loadbalancer interface.
+func (n *Notifier) Pick(e discovery.Result) discovery.Instance {
+	ins := n.lb.Pick(e)
+	if n.traceRate > 0 && atomic.AddUint32(&n.tracePicks, 1)%n.traceRate == 0 {
+		n.trace(e, ins)
+	}
+	listeners := n.listeners.Load().([]*Listener)
+	if len(listeners) > 0 {
+		event := PickEvent{Balancer: n.lb.Name(), Result: e, Instance: ins}
+		if ins == nil {
+			event.Failure = FailureReason(n.lb, e)
+		}
+		for _, l := range listeners {
+			(*l).OnPick(event)
+		}
+	}
+	return ins
+}
+
+// Rebalance implements the Loadbalancer interface.
+func (n *Notifier) Rebalance(e discovery.Result) {
+	n.lb.Rebalance(e)
+	event := RebalanceEvent{Balancer: n.lb.Name(), Result: e, Diff: n.diff(e)}
+	_, event.Malformed = SanitizeInstances(e.Instances)
+	for _, l := range n.listeners.Load().([]*Listener) {
+		(*l).OnRebalance(event)
+	}
+}
+
+func (n *Notifier) diff(e discovery.Result) Diff {
+	cur := newInstanceSet(e.Instances)
+	n.rebalanceMu.Lock()
+	defer n.rebalanceMu.Unlock()
+	old, ok := n.instances[e.CacheKey]
+	if !ok {
+		old = &instanceSet{}
+	}
+	n.instances[e.CacheKey] = cur
+	d := diff(e.CacheKey, old, cur)
+	if n.historySize > 0 && !d.Empty() {
+		if len(n.history) == n.historySize {
+			copy(n.history, n.history[1:])
+			n.history = n.history[:len(n.history)-1]
+		}
+		n.history = append(n.history, d)
+	}
+	return d
+}
+
+// Delete implements the Loadbalancer interface.
+func (n *Notifier) Delete(cacheKey string) {
+	n.lb.Delete(cacheKey)
+	n.rebalanceMu.Lock()
+	delete(n.instances, cacheKey)
+	n.rebalanceMu.Unlock()
+	event := DeleteEvent{Balancer: n.lb.Name(), CacheKey: cacheKey}
+	for _, l := range n.listeners.Load().([]*Listener) {
+		(*l).OnDelete(event)
+	}
+}
+
+// Name implements the Loadbalancer interface.
+func (n *Notifier) Name() string {
+	return n.lb.Name()
+}
+
+// Unwrap implements the Wrapper interface.
+func (n *Notifier) Unwrap() loadbalance.Loadbalancer {
+	return n.lb
+}
@@ -0,0 +1,154 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance_test
+
+import (
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+	roundrobin "github.com/hertz-contrib/loadbalance/round_robin"
+)
+
+func TestNotifier(t *testing.T) {
+	n := loadbalanceEx.NewNotifier(roundrobin.NewRoundRobinBalancer())
+	assert.DeepEqual(t, "round_robin", n.Name())
+
+	var picks []loadbalanceEx.PickEvent
+	var rebalances []loadbalanceEx.RebalanceEvent
+	var deletes []loadbalanceEx.DeleteEvent
+	remove := n.AddListener(loadbalanceEx.SynthesizedListener{
+		PickFunc:      func(e loadbalanceEx.PickEvent) { picks = append(picks, e) },
+		RebalanceFunc: func(e loadbalanceEx.RebalanceEvent) { rebalances = append(rebalances, e) },
+		DeleteFunc:    func(e loadbalanceEx.DeleteEvent) { deletes = append(deletes, e) },
+	})
+	var sampled int
+	n.AddListener(loadbalanceEx.Sampled(loadbalanceEx.SynthesizedListener{
+		PickFunc: func(e loadbalanceEx.PickEvent) { sampled++ },
+	}, 5))
+
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 10, nil),
+		},
+		CacheKey: "a",
+	}
+	n.Rebalance(e)
+	for i := 0; i < 10; i++ {
+		assert.DeepEqual(t, n.Pick(e), picks[i].Instance)
+		assert.DeepEqual(t, "round_robin", picks[i].Balancer)
+	}
+	n.Pick(discovery.Result{CacheKey: "b"})
+	assert.Nil(t, picks[10].Instance)
+	assert.DeepEqual(t, loadbalanceEx.FailureNoInstances, picks[10].Failure)
+	assert.DeepEqual(t, "", picks[9].Failure)
+	n.Delete("a")
+
+	assert.DeepEqual(t, 11, len(picks))
+	assert.DeepEqual(t, 2, sampled)
+	assert.DeepEqual(t, 1, len(rebalances))
+	assert.DeepEqual(t, "a", rebalances[0].Result.CacheKey)
+	assert.DeepEqual(t, []loadbalanceEx.DeleteEvent{{Balancer: "round_robin", CacheKey: "a"}}, deletes)
+
+	remove()
+	n.Pick(e)
+	assert.DeepEqual(t, 11, len(picks))
+}
+
+func TestNotifierDiffs(t *testing.T) {
+	n := loadbalanceEx.NewNotifier(roundrobin.NewRoundRobinBalancer(), loadbalanceEx.WithDiffHistory(2))
+	var diffs []loadbalanceEx.Diff
+	n.AddListener(loadbalanceEx.SynthesizedListener{
+		RebalanceFunc: func(e loadbalanceEx.RebalanceEvent) { diffs = append(diffs, e.Diff) },
+	})
+
+	n.Rebalance(discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 10, nil),
+		},
+		CacheKey: "a",
+	})
+	assert.DeepEqual(t, []loadbalanceEx.InstanceState{
+		{Address: "127.0.0.1:8880", Weight: 10},
+		{Address: "127.0.0.1:8881", Weight: 10},
+	}, diffs[0].Added)
+
+	// identical result
+	n.Rebalance(discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+		},
+		CacheKey: "a",
+	})
+	assert.True(t, diffs[1].Empty())
+
+	n.Rebalance(discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 20, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8882", 10, nil),
+		},
+		CacheKey: "a",
+	})
+	d := diffs[2]
+	assert.DeepEqual(t, "a", d.CacheKey)
+	assert.DeepEqual(t, []loadbalanceEx.InstanceState{{Address: "127.0.0.1:8882", Weight: 10}}, d.Added)
+	assert.DeepEqual(t, []loadbalanceEx.InstanceState{{Address: "127.0.0.1:8881", Weight: 10}}, d.Removed)
+	assert.DeepEqual(t, []loadbalanceEx.WeightChange{{Address: "127.0.0.1:8880", OldWeight: 10, NewWeight: 20}}, d.Changed)
+
+	// the empty diff is not retained, the oldest diff is evicted
+	n.Rebalance(discovery.Result{CacheKey: "b"})
+	n.Delete("a")
+	n.Rebalance(discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 20, nil),
+		},
+		CacheKey: "a",
+	})
+	history := n.Diffs()
+	assert.DeepEqual(t, 2, len(history))
+	assert.DeepEqual(t, d, history[0])
+	assert.DeepEqual(t, []loadbalanceEx.InstanceState{{Address: "127.0.0.1:8880", Weight: 20}}, history[1].Added)
+}
+
+func TestNotifierMalformed(t *testing.T) {
+	n := loadbalanceEx.NewNotifier(roundrobin.NewRoundRobinBalancer(), loadbalanceEx.WithPickTracing(1, 1))
+	var events []loadbalanceEx.RebalanceEvent
+	n.AddListener(loadbalanceEx.SynthesizedListener{
+		RebalanceFunc: func(e loadbalanceEx.RebalanceEvent) { events = append(events, e) },
+	})
+	n.AddListener(loadbalanceEx.NewFairnessTracker())
+	n.AddListener(loadbalanceEx.NewStalenessTracker())
+
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			nil,
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+		},
+		CacheKey: "a",
+	}
+	n.Rebalance(e)
+	assert.DeepEqual(t, []string{""}, events[0].Malformed)
+	assert.DeepEqual(t, []loadbalanceEx.InstanceState{{Address: "127.0.0.1:8880", Weight: 10}}, events[0].Diff.Added)
+
+	assert.DeepEqual(t, "127.0.0.1:8880", n.Pick(e).Address().String())
+	traces := n.Traces()
+	assert.DeepEqual(t, []loadbalanceEx.InstanceState{{Address: "127.0.0.1:8880", Weight: 10}}, traces[0].Candidates)
+}
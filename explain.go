@@ -0,0 +1,75 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance
+
+import (
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+)
+
+// Candidate is an instance considered by a pick.
+type Candidate struct {
+	Address string `json:"address"`
+	Weight  int    `json:"weight"`
+	// Score ranks the candidates, the chosen one has the highest score.
+	Score float64 `json:"score"`
+	// Excluded is the reason the candidate cannot be chosen, empty if it can.
+	Excluded string `json:"excluded,omitempty"`
+}
+
+// Decision explains the choice of a pick.
+type Decision struct {
+	Balancer string `json:"balancer"`
+	CacheKey string `json:"cache_key"`
+	// Instance is the chosen instance, nil if no instance can be picked.
+	Instance   discovery.Instance `json:"-"`
+	Candidates []Candidate        `json:"candidates"`
+}
+
+// Explainer is implemented by balancers able to explain their picks.
+type Explainer interface {
+	// Explain returns the decision the next Pick of e would take, without picking.
+	// Concurrent picks may change the decision before the next Pick.
+	Explain(e discovery.Result) Decision
+}
+
+// Explain returns the decision the next pick of e by lb, or by the balancer it wraps, would take.
+// It returns false if no balancer in the chain implements Explainer.
+func Explain(lb loadbalance.Loadbalancer, e discovery.Result) (Decision, bool) {
+	ex, ok := find(lb, func(lb loadbalance.Loadbalancer) bool {
+		_, ok := lb.(Explainer)
+		return ok
+	}).(Explainer)
+	if !ok {
+		return Decision{}, false
+	}
+	return ex.Explain(e), true
+}
+
+// explainAll returns the decision of lb for e, or candidates without scores if lb cannot explain its picks.
+func explainAll(lb loadbalance.Loadbalancer, e discovery.Result) Decision {
+	if d, ok := Explain(lb, e); ok {
+		return d
+	}
+	d := Decision{Balancer: lb.Name(), CacheKey: e.CacheKey}
+	for _, ins := range e.Instances {
+		if addr, ok := validAddress(ins); ok {
+			d.Candidates = append(d.Candidates, Candidate{Address: addr, Weight: ins.Weight()})
+		}
+	}
+	return d
+}
@@ -0,0 +1,32 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance
+
+import (
+	"expvar"
+
+	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+)
+
+// Publish exports the states of lb as an expvar variable with the given name,
+// so that they are served by the /debug/vars endpoint.
+// Like expvar.Publish, it panics if the name is already registered.
+func Publish(name string, lb loadbalance.Loadbalancer) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return States(lb)
+	}))
+}
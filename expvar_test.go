@@ -0,0 +1,63 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+	roundrobin "github.com/hertz-contrib/loadbalance/round_robin"
+)
+
+type wrapped struct {
+	loadbalance.Loadbalancer
+}
+
+func (w wrapped) Unwrap() loadbalance.Loadbalancer {
+	return w.Loadbalancer
+}
+
+func TestPublish(t *testing.T) {
+	lb := wrapped{roundrobin.NewRoundRobinBalancer()}
+	lb.Rebalance(discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+		},
+		CacheKey: "a",
+	})
+	loadbalanceEx.Publish("loadbalance_test", lb)
+
+	var states []loadbalanceEx.State
+	err := json.Unmarshal([]byte(expvar.Get("loadbalance_test").String()), &states)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, loadbalanceEx.States(lb), states)
+	assert.DeepEqual(t, "127.0.0.1:8880", states[0].Instances[0].Address)
+
+	state, ok := loadbalanceEx.Snapshot(lb, "a")
+	assert.True(t, ok)
+	assert.DeepEqual(t, states[0], state)
+
+	// balancers without state
+	assert.Nil(t, loadbalanceEx.States(loadbalance.NewWeightedBalancer()))
+	_, ok = loadbalanceEx.Snapshot(loadbalance.NewWeightedBalancer(), "a")
+	assert.False(t, ok)
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance
+
+import (
+	"errors"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+)
+
+// Reasons why a pick returned no instance, see FailureReason.
+const (
+	// FailureNoInstances means that there was no instance to pick from.
+	FailureNoInstances = "no_instances"
+	// FailureZeroWeight means that the total weight of the instances is zero.
+	FailureZeroWeight = "zero_weight"
+	// FailureAllExcluded means that every instance was excluded by the balancer.
+	FailureAllExcluded = "all_excluded"
+	// FailureUnknown means that the balancer cannot tell why it returned no instance.
+	FailureUnknown = "unknown"
+)
+
+// FailureReason returns why lb returned no instance for e, as one of the Failure constants.
+// It must be called right after the failed pick since it relies on Explain if lb,
+// or the balancer it wraps, implements Explainer.
+func FailureReason(lb loadbalance.Loadbalancer, e discovery.Result) string {
+	d, ok := Explain(lb, e)
+	if !ok {
+		if len(e.Instances) == 0 {
+			return FailureNoInstances
+		}
+		return FailureUnknown
+	}
+	return d.failureReason()
+}
+
+func (d Decision) failureReason() string {
+	if len(d.Candidates) == 0 {
+		return FailureNoInstances
+	}
+	// 64-bit so that large weights do not overflow on 32-bit platforms
+	weight, excluded := int64(0), 0
+	for _, c := range d.Candidates {
+		if c.Excluded != "" {
+			excluded++
+			continue
+		}
+		weight += int64(c.Weight)
+	}
+	switch {
+	case excluded == len(d.Candidates):
+		return FailureAllExcluded
+	case weight <= 0:
+		return FailureZeroWeight
+	default:
+		return FailureUnknown
+	}
+}
+
+// Errors returned by TryPick, one per failure reason.
+var (
+	ErrNoInstances  = errors.New("loadbalance: no instances")
+	ErrZeroWeight   = errors.New("loadbalance: total weight of the instances is zero")
+	ErrAllExcluded  = errors.New("loadbalance: all instances are excluded")
+	ErrNoneReturned = errors.New("loadbalance: balancer returned no instance")
+)
+
+var failureErrors = map[string]error{
+	FailureNoInstances: ErrNoInstances,
+	FailureZeroWeight:  ErrZeroWeight,
+	FailureAllExcluded: ErrAllExcluded,
+	FailureUnknown:     ErrNoneReturned,
+}
+
+// TryPick picks an instance of e with lb, and returns an error telling why if lb returns none.
+func TryPick(lb loadbalance.Loadbalancer, e discovery.Result) (discovery.Instance, error) {
+	if ins := lb.Pick(e); ins != nil {
+		return ins, nil
+	}
+	return nil, failureErrors[FailureReason(lb, e)]
+}
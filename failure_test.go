@@ -0,0 +1,78 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance_test
+
+import (
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+	roundrobin "github.com/hertz-contrib/loadbalance/round_robin"
+)
+
+func TestFailureReason(t *testing.T) {
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+		},
+		CacheKey: "a",
+	}
+	// without Explainer only an empty result can be told apart
+	assert.DeepEqual(t, loadbalanceEx.FailureNoInstances, loadbalanceEx.FailureReason(firstBalancer{}, discovery.Result{CacheKey: "a"}))
+	assert.DeepEqual(t, loadbalanceEx.FailureUnknown, loadbalanceEx.FailureReason(firstBalancer{}, e))
+
+	lb := roundrobin.NewRoundRobinBalancer()
+	assert.DeepEqual(t, loadbalanceEx.FailureNoInstances, loadbalanceEx.FailureReason(lb, discovery.Result{CacheKey: "a"}))
+
+	// the empty instance list is cached until the next rebalance
+	lb.Rebalance(discovery.Result{CacheKey: "a"})
+	assert.Nil(t, lb.Pick(e))
+	assert.DeepEqual(t, loadbalanceEx.FailureAllExcluded, loadbalanceEx.FailureReason(wrapped{lb}, e))
+}
+
+func TestTryPick(t *testing.T) {
+	lb := roundrobin.NewRoundRobinBalancer()
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+		},
+		CacheKey: "a",
+	}
+	ins, err := loadbalanceEx.TryPick(lb, e)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, e.Instances[0], ins)
+
+	_, err = loadbalanceEx.TryPick(lb, discovery.Result{CacheKey: "b"})
+	assert.DeepEqual(t, loadbalanceEx.ErrNoInstances, err)
+
+	lb.Rebalance(discovery.Result{CacheKey: "a"})
+	_, err = loadbalanceEx.TryPick(lb, e)
+	assert.DeepEqual(t, loadbalanceEx.ErrAllExcluded, err)
+
+	_, err = loadbalanceEx.TryPick(nilBalancer{}, e)
+	assert.DeepEqual(t, loadbalanceEx.ErrNoneReturned, err)
+}
+
+// nilBalancer never picks an instance.
+type nilBalancer struct {
+	firstBalancer
+}
+
+func (nilBalancer) Pick(discovery.Result) discovery.Instance {
+	return nil
+}
@@ -0,0 +1,256 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+)
+
+// Expectation is how the picks of a cache key are expected to be split between its instances.
+type Expectation int
+
+const (
+	// ExpectWeighted expects the picks of an instance in proportion to its weight.
+	ExpectWeighted Expectation = iota
+	// ExpectUniform expects the same picks for every instance whatever its weight, as round-robin does.
+	ExpectUniform
+)
+
+// WeightAware is implemented by balancers telling whether their picks follow the weights of the instances.
+type WeightAware interface {
+	// Weighted returns whether the picks follow the weights of the instances.
+	Weighted() bool
+}
+
+// ExpectationOf returns the Expectation matching the picks of lb, or of the balancer it wraps:
+// ExpectUniform if it implements WeightAware and ignores the weights, ExpectWeighted otherwise.
+func ExpectationOf(lb loadbalance.Loadbalancer) Expectation {
+	if wa, ok := find(lb, func(lb loadbalance.Loadbalancer) bool {
+		_, ok := lb.(WeightAware)
+		return ok
+	}).(WeightAware); ok && !wa.Weighted() {
+		return ExpectUniform
+	}
+	return ExpectWeighted
+}
+
+// FairnessTrackerOption configures a FairnessTracker.
+type FairnessTrackerOption func(ft *FairnessTracker)
+
+// WithExpectation sets how the picks are expected to be split, ExpectWeighted by default.
+// Use ExpectationOf the tracked balancer, so that balancers ignoring the weights are not reported as skewed.
+func WithExpectation(expect Expectation) FairnessTrackerOption {
+	return func(ft *FairnessTracker) {
+		ft.expect = expect
+	}
+}
+
+// InstanceFairness compares the picks of an instance with what it is expected to get, see Expectation.
+type InstanceFairness struct {
+	Address  string  `json:"address"`
+	Weight   int     `json:"weight"`
+	Picks    uint64  `json:"picks"`
+	Expected float64 `json:"expected"`
+	// Deviation is (Picks - Expected) / Expected, 0 if nothing is expected.
+	Deviation float64 `json:"deviation"`
+}
+
+// FairnessReport describes how far the picks of a cache key are from the expected ones.
+type FairnessReport struct {
+	CacheKey  string             `json:"cache_key"`
+	Since     time.Time          `json:"since"`
+	Picks     uint64             `json:"picks"`
+	Instances []InstanceFairness `json:"instances"`
+	// ChiSquare is Pearson's chi-square statistic of the picks against the expected picks,
+	// with len(Instances)-1 degrees of freedom.
+	ChiSquare float64 `json:"chi_square"`
+	// MaxDeviation is the largest absolute Deviation of the instances.
+	MaxDeviation float64 `json:"max_deviation"`
+}
+
+type fairnessWindow struct {
+	expect  Expectation
+	since   time.Time
+	weights map[string]int
+	picks   map[string]uint64
+}
+
+// FairnessTracker is a Listener accumulating the picks of each instance over a window,
+// so that the real traffic split can be compared with the expected one, see WithExpectation.
+// Register it on a Notifier without sampling: round-robin picks are periodic,
+// so sampling them with Sampled biases the report.
+type FairnessTracker struct {
+	expect  Expectation
+	mu      sync.Mutex
+	windows map[string]*fairnessWindow
+}
+
+// NewFairnessTracker creates a FairnessTracker.
+func NewFairnessTracker(opts ...FairnessTrackerOption) *FairnessTracker {
+	ft := &FairnessTracker{
+		windows: make(map[string]*fairnessWindow),
+	}
+	for _, opt := range opts {
+		opt(ft)
+	}
+	return ft
+}
+
+func newFairnessWindow(expect Expectation, instances []discovery.Instance) *fairnessWindow {
+	w := &fairnessWindow{
+		expect: expect,
+		since:  time.Now(),
+		picks:  make(map[string]uint64, len(instances)),
+	}
+	w.setWeights(instances)
+	return w
+}
+
+func (w *fairnessWindow) setWeights(instances []discovery.Instance) {
+	w.weights = make(map[string]int, len(instances))
+	for _, ins := range instances {
+		if addr, ok := validAddress(ins); ok {
+			w.weights[addr] = ins.Weight()
+		}
+	}
+	for addr := range w.picks {
+		if _, ok := w.weights[addr]; !ok {
+			delete(w.picks, addr)
+		}
+	}
+}
+
+// OnPick implements the Listener interface.
+func (ft *FairnessTracker) OnPick(e PickEvent) {
+	if e.Instance == nil {
+		return
+	}
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+	w, ok := ft.windows[e.Result.CacheKey]
+	if !ok {
+		w = newFairnessWindow(ft.expect, e.Result.Instances)
+		ft.windows[e.Result.CacheKey] = w
+	}
+	w.picks[e.Instance.Address().String()]++
+}
+
+// OnRebalance implements the Listener interface.
+func (ft *FairnessTracker) OnRebalance(e RebalanceEvent) {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+	if w, ok := ft.windows[e.Result.CacheKey]; ok {
+		w.setWeights(e.Result.Instances)
+		return
+	}
+	ft.windows[e.Result.CacheKey] = newFairnessWindow(ft.expect, e.Result.Instances)
+}
+
+// OnDelete implements the Listener interface.
+func (ft *FairnessTracker) OnDelete(e DeleteEvent) {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+	delete(ft.windows, e.CacheKey)
+}
+
+// Report returns the fairness of the picks of cacheKey since the window started,
+// or false if nothing is known about cacheKey.
+func (ft *FairnessTracker) Report(cacheKey string) (FairnessReport, bool) {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+	w, ok := ft.windows[cacheKey]
+	if !ok {
+		return FairnessReport{}, false
+	}
+	return w.report(cacheKey), true
+}
+
+// Reports returns the fairness of the picks of every cache key, sorted by cache key.
+func (ft *FairnessTracker) Reports() []FairnessReport {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+	reports := make([]FairnessReport, 0, len(ft.windows))
+	for key, w := range ft.windows {
+		reports = append(reports, w.report(key))
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].CacheKey < reports[j].CacheKey
+	})
+	return reports
+}
+
+// Reset starts a new window for every cache key.
+func (ft *FairnessTracker) Reset() {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+	now := time.Now()
+	for _, w := range ft.windows {
+		w.since = now
+		w.picks = make(map[string]uint64, len(w.weights))
+	}
+}
+
+// share returns the share of the picks expected for an instance of the weight.
+func (w *fairnessWindow) share(weight int) int64 {
+	if w.expect == ExpectUniform {
+		return 1
+	}
+	return int64(weight)
+}
+
+func (w *fairnessWindow) report(cacheKey string) FairnessReport {
+	r := FairnessReport{
+		CacheKey:  cacheKey,
+		Since:     w.since,
+		Instances: make([]InstanceFairness, 0, len(w.weights)),
+	}
+	// 64-bit so that large weights do not overflow on 32-bit platforms
+	var weightSum int64
+	for addr, weight := range w.weights {
+		if share := w.share(weight); share > 0 {
+			weightSum += share
+		}
+		r.Picks += w.picks[addr]
+	}
+	for addr, weight := range w.weights {
+		f := InstanceFairness{
+			Address: addr,
+			Weight:  weight,
+			Picks:   w.picks[addr],
+		}
+		if share := w.share(weight); share > 0 && weightSum > 0 {
+			f.Expected = float64(r.Picks) * float64(share) / float64(weightSum)
+		}
+		if f.Expected > 0 {
+			diff := float64(f.Picks) - f.Expected
+			f.Deviation = diff / f.Expected
+			r.ChiSquare += diff * diff / f.Expected
+			r.MaxDeviation = math.Max(r.MaxDeviation, math.Abs(f.Deviation))
+		}
+		r.Instances = append(r.Instances, f)
+	}
+	sort.Slice(r.Instances, func(i, j int) bool {
+		return r.Instances[i].Address < r.Instances[j].Address
+	})
+	return r
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance_test
+
+import (
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+	roundrobin "github.com/hertz-contrib/loadbalance/round_robin"
+)
+
+func TestFairnessTracker(t *testing.T) {
+	ft := loadbalanceEx.NewFairnessTracker()
+	n := loadbalanceEx.NewNotifier(roundrobin.NewRoundRobinBalancer())
+	n.AddListener(ft)
+
+	_, ok := ft.Report("a")
+	assert.False(t, ok)
+
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 30, nil),
+		},
+		CacheKey: "a",
+	}
+	for i := 0; i < 100; i++ {
+		n.Pick(e)
+	}
+	// round robin ignores weights: 50 picks each while 25 and 75 are expected
+	r, ok := ft.Report("a")
+	assert.True(t, ok)
+	assert.DeepEqual(t, uint64(100), r.Picks)
+	assert.DeepEqual(t, []loadbalanceEx.InstanceFairness{
+		{Address: "127.0.0.1:8880", Weight: 10, Picks: 50, Expected: 25, Deviation: 1},
+		{Address: "127.0.0.1:8881", Weight: 30, Picks: 50, Expected: 75, Deviation: -1.0 / 3},
+	}, r.Instances)
+	assert.DeepEqual(t, 25.0+625.0/75, r.ChiSquare)
+	assert.DeepEqual(t, 1.0, r.MaxDeviation)
+
+	// equal weights, fair split
+	e.Instances[1] = discovery.NewInstance("tcp", "127.0.0.1:8881", 10, nil)
+	n.Rebalance(e)
+	ft.Reset()
+	for i := 0; i < 100; i++ {
+		n.Pick(e)
+	}
+	r, _ = ft.Report("a")
+	assert.DeepEqual(t, 0.0, r.ChiSquare)
+	assert.DeepEqual(t, 0.0, r.MaxDeviation)
+	assert.DeepEqual(t, 1, len(ft.Reports()))
+
+	n.Delete("a")
+	assert.DeepEqual(t, 0, len(ft.Reports()))
+}
+
+func TestFairnessExpectation(t *testing.T) {
+	lb := roundrobin.NewRoundRobinBalancer()
+	assert.DeepEqual(t, loadbalanceEx.ExpectUniform, loadbalanceEx.ExpectationOf(wrapped{lb}))
+	assert.DeepEqual(t, loadbalanceEx.ExpectWeighted, loadbalanceEx.ExpectationOf(firstBalancer{}))
+
+	ft := loadbalanceEx.NewFairnessTracker(loadbalanceEx.WithExpectation(loadbalanceEx.ExpectationOf(lb)))
+	n := loadbalanceEx.NewNotifier(lb)
+	n.AddListener(ft)
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 30, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8882", 0, nil),
+		},
+		CacheKey: "a",
+	}
+	for i := 0; i < 99; i++ {
+		n.Pick(e)
+	}
+	// unequal weights are not a skew for round-robin
+	r, _ := ft.Report("a")
+	assert.DeepEqual(t, 0.0, r.MaxDeviation)
+	assert.DeepEqual(t, 33.0, r.Instances[1].Expected)
+}
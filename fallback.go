@@ -0,0 +1,74 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance
+
+import (
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+)
+
+// Fallback is a Loadbalancer picking with a chain of balancers: when a balancer returns no instance,
+// the next one is asked. Every balancer of the chain receives the rebalances and deletes.
+type Fallback struct {
+	chain []loadbalance.Loadbalancer
+}
+
+// NewFallback creates a Fallback picking with primary, then with fallbacks in order.
+func NewFallback(primary loadbalance.Loadbalancer, fallbacks ...loadbalance.Loadbalancer) *Fallback {
+	return &Fallback{chain: append([]loadbalance.Loadbalancer{primary}, fallbacks...)}
+}
+
+// Pick implements the Loadbalancer interface.
+func (f *Fallback) Pick(e discovery.Result) discovery.Instance {
+	for _, lb := range f.chain {
+		if ins := lb.Pick(e); ins != nil {
+			return ins
+		}
+	}
+	return nil
+}
+
+// Rebalance implements the Loadbalancer interface.
+func (f *Fallback) Rebalance(e discovery.Result) {
+	for _, lb := range f.chain {
+		lb.Rebalance(e)
+	}
+}
+
+// Prime implements the Primer interface, it primes every balancer of the chain.
+func (f *Fallback) Prime(e discovery.Result) {
+	for _, lb := range f.chain {
+		Prime(lb, e)
+	}
+}
+
+// Delete implements the Loadbalancer interface.
+func (f *Fallback) Delete(cacheKey string) {
+	for _, lb := range f.chain {
+		lb.Delete(cacheKey)
+	}
+}
+
+// Name implements the Loadbalancer interface.
+func (f *Fallback) Name() string {
+	return "fallback"
+}
+
+// Unwrap implements the Wrapper interface, it returns the primary balancer.
+func (f *Fallback) Unwrap() loadbalance.Loadbalancer {
+	return f.chain[0]
+}
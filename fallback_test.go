@@ -0,0 +1,52 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance_test
+
+import (
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+	roundrobin "github.com/hertz-contrib/loadbalance/round_robin"
+)
+
+func TestFallback(t *testing.T) {
+	primary := roundrobin.NewRoundRobinBalancer()
+	f := loadbalanceEx.NewFallback(primary, firstBalancer{})
+	assert.DeepEqual(t, "fallback", f.Name())
+
+	e := discovery.Result{
+		Instances: []discovery.Instance{discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil)},
+		CacheKey:  "a",
+	}
+	// the primary has cached no instance for the cache key
+	f.Rebalance(discovery.Result{CacheKey: "a"})
+	assert.Nil(t, primary.Pick(e))
+	assert.DeepEqual(t, e.Instances[0], f.Pick(e))
+
+	f.Rebalance(e)
+	assert.DeepEqual(t, e.Instances[0], f.Pick(e))
+	state, ok := loadbalanceEx.Snapshot(f, "a")
+	assert.True(t, ok)
+	assert.DeepEqual(t, uint64(1), state.Instances[0].Picks)
+
+	f.Delete("a")
+	_, ok = loadbalanceEx.Snapshot(f, "a")
+	assert.False(t, ok)
+	assert.Nil(t, f.Pick(discovery.Result{CacheKey: "b"}))
+}
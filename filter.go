@@ -0,0 +1,62 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package loadbalance
+
+import (
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+)
+
+const filterMatch = "match"
+
+// TagFilter is a Loadbalancer restricting the picks of the wrapped balancer to the instances carrying required
+// tags, e.g. env=prod, so that registries mixing environments do not leak staging instances into production
+// traffic. Picks fail when no instance matches. The wrapped balancer holds the state of the matching instances
+// under the cache key suffixed with "#match".
+//
+// Balancers do not see the context of a request. To filter per request, force the tags with WithForcedTags
+// and a resolver targeting the instances by the tags of the request.
+type TagFilter struct {
+	splitter
+}
+
+// NewTagFilter creates a TagFilter picking with lb among the instances whose tags have the values of required.
+func NewTagFilter(lb loadbalance.Loadbalancer, required map[string]string) *TagFilter {
+	tags := make(map[string]string, len(required))
+	for k, v := range required {
+		tags[k] = v
+	}
+	f := &TagFilter{splitter{lb: lb, strict: true, excluded: "missing required tags", key: func(ins discovery.Instance) (string, bool) {
+		for k, v := range tags {
+			if tag, _ := ins.Tag(k); tag != v {
+				return "", false
+			}
+		}
+		return filterMatch, true
+	}}}
+	f.setWeights(map[string]int{filterMatch: 1})
+	return f
+}
+
+// Explain implements the Explainer interface.
+func (f *TagFilter) Explain(e discovery.Result) Decision {
+	return f.explain(f.Name(), e)
+}
+
+// Name implements the Loadbalancer interface.
+func (f *TagFilter) Name() string {
+	return "tag_filter"
+}
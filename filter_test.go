@@ -0,0 +1,76 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package loadbalance_test
+
+import (
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+	roundrobin "github.com/hertz-contrib/loadbalance/round_robin"
+)
+
+func TestTagFilter(t *testing.T) {
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, map[string]string{"env": "prod", "protocol": "grpc"}),
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 10, map[string]string{"env": "staging", "protocol": "grpc"}),
+			discovery.NewInstance("tcp", "127.0.0.1:8882", 10, map[string]string{"env": "prod"}),
+			discovery.NewInstance("tcp", "127.0.0.1:8883", 10, map[string]string{"env": "prod", "protocol": "grpc"}),
+		},
+		CacheKey: "a",
+	}
+	f := loadbalanceEx.NewTagFilter(roundrobin.NewRoundRobinBalancer(), map[string]string{"env": "prod", "protocol": "grpc"})
+	picks := make(map[string]int)
+	for i := 0; i < 10; i++ {
+		picks[f.Pick(e).Address().String()]++
+	}
+	assert.DeepEqual(t, map[string]int{"127.0.0.1:8880": 5, "127.0.0.1:8883": 5}, picks)
+
+	// the filtered out instances are not cached
+	f.Rebalance(e)
+	f.Prime(e)
+	_, ok := loadbalanceEx.Snapshot(f, "a")
+	assert.False(t, ok)
+	for _, state := range loadbalanceEx.States(f) {
+		assert.DeepEqual(t, "a#match", state.CacheKey)
+	}
+
+	// the explanation is the one of the filter, not of the wrapped balancer
+	d, ok := loadbalanceEx.Explain(f, e)
+	assert.True(t, ok)
+	assert.DeepEqual(t, "tag_filter", d.Balancer)
+	assert.DeepEqual(t, "127.0.0.1:8880", d.Instance.Address().String())
+	excluded := make(map[string]string)
+	for _, c := range d.Candidates {
+		excluded[c.Address] = c.Excluded
+	}
+	assert.DeepEqual(t, map[string]string{
+		"127.0.0.1:8880": "",
+		"127.0.0.1:8881": "missing required tags",
+		"127.0.0.1:8882": "missing required tags",
+		"127.0.0.1:8883": "",
+	}, excluded)
+
+	// no instance matches
+	e.Instances = e.Instances[1:3]
+	f.Rebalance(e)
+	assert.Nil(t, f.Pick(e))
+	_, err := loadbalanceEx.TryPick(f, e)
+	assert.DeepEqual(t, loadbalanceEx.ErrAllExcluded, err)
+	assert.DeepEqual(t, "tag_filter", f.Name())
+}
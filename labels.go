@@ -0,0 +1,76 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance
+
+import (
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+)
+
+// MetricLabels describes the labels the metrics packages attach to the exported metrics,
+// in addition to the name of the balancer. Deriving few labels with few distinct values
+// keeps the cardinality of the metrics under control.
+type MetricLabels struct {
+	// Names are the labels of every metric.
+	Names []string
+	// Values returns the values of Names for a cache key.
+	Values func(cacheKey string) []string
+	// InstanceNames are the additional labels of the metrics related to an instance, such as picks.
+	InstanceNames []string
+	// InstanceValues returns the values of InstanceNames for an instance.
+	InstanceValues func(ins discovery.Instance) []string
+}
+
+// DefaultMetricLabels labels every metric with the cache key,
+// and the metrics related to an instance with its address.
+var DefaultMetricLabels = MetricLabels{
+	Names: []string{"cache_key"},
+	Values: func(cacheKey string) []string {
+		return []string{cacheKey}
+	},
+	InstanceNames: []string{"address"},
+	InstanceValues: func(ins discovery.Instance) []string {
+		return []string{ins.Address().String()}
+	},
+}
+
+// ValuesOf returns the values of Names for cacheKey, one for each name.
+func (ml MetricLabels) ValuesOf(cacheKey string) []string {
+	var values []string
+	if ml.Values != nil {
+		values = ml.Values(cacheKey)
+	}
+	return fit(values, len(ml.Names))
+}
+
+// InstanceValuesOf returns the values of InstanceNames for ins, one for each name.
+func (ml MetricLabels) InstanceValuesOf(ins discovery.Instance) []string {
+	var values []string
+	if ml.InstanceValues != nil {
+		values = ml.InstanceValues(ins)
+	}
+	return fit(values, len(ml.InstanceNames))
+}
+
+// fit truncates values to n values, or pads them with empty values.
+func fit(values []string, n int) []string {
+	if len(values) == n {
+		return values
+	}
+	res := make([]string, n)
+	copy(res, values)
+	return res
+}
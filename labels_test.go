@@ -0,0 +1,49 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+)
+
+func TestMetricLabels(t *testing.T) {
+	ins := discovery.NewInstance("tcp", "127.0.0.1:8880", 10, map[string]string{"zone": "a"})
+	assert.DeepEqual(t, []string{"nacos:svc"}, loadbalanceEx.DefaultMetricLabels.ValuesOf("nacos:svc"))
+	assert.DeepEqual(t, []string{"127.0.0.1:8880"}, loadbalanceEx.DefaultMetricLabels.InstanceValuesOf(ins))
+
+	labels := loadbalanceEx.MetricLabels{
+		Names: []string{"resolver", "service"},
+		Values: func(cacheKey string) []string {
+			return strings.SplitN(cacheKey, ":", 2)
+		},
+		InstanceNames: []string{"zone"},
+		InstanceValues: func(ins discovery.Instance) []string {
+			zone, _ := ins.Tag("zone")
+			return []string{zone}
+		},
+	}
+	assert.DeepEqual(t, []string{"nacos", "svc"}, labels.ValuesOf("nacos:svc"))
+	// missing values are empty
+	assert.DeepEqual(t, []string{"svc", ""}, labels.ValuesOf("svc"))
+	assert.DeepEqual(t, []string{"a"}, labels.InstanceValuesOf(ins))
+	assert.DeepEqual(t, 0, len(loadbalanceEx.MetricLabels{}.ValuesOf("svc")))
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package loadbalance
+
+import (
+	"math"
+	"sync/atomic"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+	"github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/protocol"
+)
+
+const (
+	mirrorPrimary = "primary"
+	mirrorShadow  = "shadow"
+)
+
+// Mirror is a Loadbalancer keeping a shadow group of instances, those matching an expression over their tags
+// such as `shadow == "true"`, out of the picks, and mirroring a fraction of the requests to them with
+// MirrorRequest, to try a new version on production traffic without serving its responses.
+// Like Split, all the instances are picked when they all belong to the shadow group.
+type Mirror struct {
+	// accessed atomically, first for 64-bit alignment
+	mirrors  uint64
+	fraction uint64 // float64 bits
+
+	splitter
+}
+
+// NewMirror creates a Mirror picking with lb among the instances not matching shadow,
+// and mirroring fraction of the requests to the instances matching it.
+func NewMirror(lb loadbalance.Loadbalancer, shadow *TagExpr, fraction float64) *Mirror {
+	m := &Mirror{splitter: splitter{lb: lb, excluded: "shadow instance", key: func(ins discovery.Instance) (string, bool) {
+		if shadow.Match(ins) {
+			return mirrorShadow, true
+		}
+		return mirrorPrimary, true
+	}}}
+	m.setWeights(map[string]int{mirrorPrimary: 1})
+	m.SetFraction(fraction)
+	return m
+}
+
+// SetFraction sets the fraction of requests, between 0 and 1, mirrored to the shadow group.
+func (m *Mirror) SetFraction(fraction float64) {
+	f := math.Min(math.Max(fraction, 0), 1)
+	atomic.StoreUint64(&m.fraction, math.Float64bits(f))
+}
+
+// Fraction returns the fraction of requests mirrored to the shadow group.
+func (m *Mirror) Fraction() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&m.fraction))
+}
+
+// PickShadow returns a shadow instance of the cache key for a fraction of its calls, spread evenly, or false.
+// It returns false for the cache keys not rebalanced nor picked yet, and those without shadow instances.
+func (m *Mirror) PickShadow(cacheKey string) (discovery.Instance, bool) {
+	groups, ok := m.groups.Load(cacheKey)
+	if !ok {
+		return nil, false
+	}
+	g := groups.(map[string]discovery.Result)[mirrorShadow]
+	if len(g.Instances) == 0 {
+		return nil, false
+	}
+	// the shadow serves the calls where n*f reaches the next integer
+	n, f := atomic.AddUint64(&m.mirrors, 1), m.Fraction()
+	if math.Floor(float64(n)*f) == math.Floor(float64(n-1)*f) {
+		return nil, false
+	}
+	ins := m.lb.Pick(g)
+	return ins, ins != nil
+}
+
+// MirrorRequest sends a copy of req to a shadow instance of the cache key, for a fraction of its calls, and
+// returns whether it did. The copy bypasses service discovery and is sent by send in a new goroutine, whose
+// response must be discarded; it is released once send returns. It is meant to be called by a client
+// middleware used before sd.Discovery, e.g. with send calling Do on the client with a background context.
+func (m *Mirror) MirrorRequest(cacheKey string, req *protocol.Request, send func(req *protocol.Request)) bool {
+	ins, ok := m.PickShadow(cacheKey)
+	if !ok {
+		return false
+	}
+	shadow := protocol.AcquireRequest()
+	req.CopyTo(shadow)
+	shadow.SetOptions(config.WithSD(false))
+	shadow.SetHost(ins.Address().String())
+	go func() {
+		defer protocol.ReleaseRequest(shadow)
+		send(shadow)
+	}()
+	return true
+}
+
+// Explain implements the Explainer interface.
+func (m *Mirror) Explain(e discovery.Result) Decision {
+	return m.explain(m.Name(), e)
+}
+
+// Name implements the Loadbalancer interface.
+func (m *Mirror) Name() string {
+	return "mirror"
+}
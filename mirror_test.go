@@ -0,0 +1,69 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package loadbalance_test
+
+import (
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	"github.com/cloudwego/hertz/pkg/protocol"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+	roundrobin "github.com/hertz-contrib/loadbalance/round_robin"
+)
+
+func TestMirror(t *testing.T) {
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 10, map[string]string{"shadow": "true"}),
+		},
+		CacheKey: "a",
+	}
+	m := loadbalanceEx.NewMirror(roundrobin.NewRoundRobinBalancer(), loadbalanceEx.MustParseTagExpr(`shadow == "true"`), 0.1)
+	_, ok := m.PickShadow("a")
+	assert.False(t, ok)
+
+	m.Rebalance(e)
+	mirrored := 0
+	for i := 0; i < 100; i++ {
+		// the shadow instance is never picked
+		assert.DeepEqual(t, "127.0.0.1:8880", m.Pick(e).Address().String())
+		if ins, ok := m.PickShadow("a"); ok {
+			assert.DeepEqual(t, "127.0.0.1:8881", ins.Address().String())
+			mirrored++
+		}
+	}
+	assert.DeepEqual(t, 10, mirrored)
+
+	m.SetFraction(1)
+	req := protocol.AcquireRequest()
+	defer protocol.ReleaseRequest(req)
+	req.SetRequestURI("http://hertz.test.demo/ping")
+	req.SetBodyString("hello")
+	sent := make(chan string, 1)
+	assert.True(t, m.MirrorRequest("a", req, func(shadow *protocol.Request) {
+		assert.False(t, shadow.Options().IsSD())
+		sent <- string(shadow.Host()) + " " + string(shadow.Body()) + " " + string(shadow.Path())
+	}))
+	assert.DeepEqual(t, "127.0.0.1:8881 hello /ping", <-sent)
+	// the request itself is untouched
+	assert.DeepEqual(t, "hertz.test.demo", string(req.Host()))
+
+	m.SetFraction(0)
+	assert.False(t, m.MirrorRequest("a", req, func(*protocol.Request) {}))
+	assert.DeepEqual(t, "mirror", m.Name())
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package opentelemetry
+
+import (
+	"context"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric/instrument"
+	"go.opentelemetry.io/otel/metric/instrument/syncfloat64"
+	"go.opentelemetry.io/otel/metric/instrument/syncint64"
+	"go.opentelemetry.io/otel/metric/unit"
+)
+
+const instrumentationName = "github.com/hertz-contrib/loadbalance/opentelemetry"
+
+const (
+	balancerKey = attribute.Key("loadbalance.balancer")
+	addressKey  = attribute.Key("loadbalance.address")
+	reasonKey   = attribute.Key("loadbalance.reason")
+)
+
+type balancer struct {
+	lb     loadbalance.Loadbalancer
+	labels loadbalanceEx.MetricLabels
+
+	picks        syncint64.Counter
+	pickFailures syncint64.Counter
+	pickDuration syncfloat64.Histogram
+	rebalances   syncint64.Counter
+	deletes      syncint64.Counter
+}
+
+// NewBalancer wraps lb so that its picks, pick latency, rebalances and deletes are recorded as OpenTelemetry metrics.
+func NewBalancer(lb loadbalance.Loadbalancer, opts ...Option) loadbalance.Loadbalancer {
+	cfg := newConfig(opts)
+	meter := cfg.meterProvider.Meter(instrumentationName)
+
+	b := &balancer{lb: lb, labels: cfg.labels}
+	var err error
+	if b.picks, err = meter.SyncInt64().Counter("loadbalance.picks",
+		instrument.WithDescription("Number of times an instance was picked.")); err != nil {
+		otel.Handle(err)
+	}
+	if b.pickFailures, err = meter.SyncInt64().Counter("loadbalance.pick_failures",
+		instrument.WithDescription("Number of picks that returned no instance, by reason.")); err != nil {
+		otel.Handle(err)
+	}
+	if b.pickDuration, err = meter.SyncFloat64().Histogram("loadbalance.pick.duration",
+		instrument.WithDescription("Time spent picking an instance."),
+		instrument.WithUnit(unit.Milliseconds)); err != nil {
+		otel.Handle(err)
+	}
+	if b.rebalances, err = meter.SyncInt64().Counter("loadbalance.rebalances",
+		instrument.WithDescription("Number of rebalances.")); err != nil {
+		otel.Handle(err)
+	}
+	if b.deletes, err = meter.SyncInt64().Counter("loadbalance.deletes",
+		instrument.WithDescription("Number of deleted cache entries.")); err != nil {
+		otel.Handle(err)
+	}
+	return b
+}
+
+// Pick implements the Loadbalancer interface.
+func (b *balancer) Pick(e discovery.Result) discovery.Instance {
+	start := time.Now()
+	ins := b.lb.Pick(e)
+	elapsed := float64(time.Since(start)) / float64(time.Millisecond)
+
+	ctx := context.Background()
+	attrs := b.attributes(e.CacheKey)
+	b.pickDuration.Record(ctx, elapsed, attrs...)
+	if ins == nil {
+		b.pickFailures.Add(ctx, 1, append(attrs, reasonKey.String(loadbalanceEx.FailureReason(b.lb, e)))...)
+		return nil
+	}
+	for i, value := range b.labels.InstanceValuesOf(ins) {
+		attrs = append(attrs, attribute.String("loadbalance."+b.labels.InstanceNames[i], value))
+	}
+	b.picks.Add(ctx, 1, attrs...)
+	return ins
+}
+
+// Rebalance implements the Loadbalancer interface.
+func (b *balancer) Rebalance(e discovery.Result) {
+	b.lb.Rebalance(e)
+	b.rebalances.Add(context.Background(), 1, b.attributes(e.CacheKey)...)
+}
+
+// Delete implements the Loadbalancer interface.
+func (b *balancer) Delete(cacheKey string) {
+	b.lb.Delete(cacheKey)
+	b.deletes.Add(context.Background(), 1, b.attributes(cacheKey)...)
+}
+
+// attributes returns the attributes of the instruments for cacheKey.
+// The slice has spare capacity for the instance attributes, or the failure reason.
+func (b *balancer) attributes(cacheKey string) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, 2+len(b.labels.Names)+len(b.labels.InstanceNames))
+	attrs = append(attrs, balancerKey.String(b.lb.Name()))
+	for i, value := range b.labels.ValuesOf(cacheKey) {
+		attrs = append(attrs, attribute.String("loadbalance."+b.labels.Names[i], value))
+	}
+	return attrs
+}
+
+// Name implements the Loadbalancer interface.
+func (b *balancer) Name() string {
+	return b.lb.Name()
+}
+
+// Unwrap returns the wrapped Loadbalancer.
+func (b *balancer) Unwrap() loadbalance.Loadbalancer {
+	return b.lb
+}
@@ -0,0 +1,137 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package opentelemetry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	hertzconfig "github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	"github.com/cloudwego/hertz/pkg/protocol"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+	roundrobin "github.com/hertz-contrib/loadbalance/round_robin"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestBalancer(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	lb := NewBalancer(roundrobin.NewRoundRobinBalancer(),
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))))
+	assert.DeepEqual(t, "round_robin", lb.Name())
+
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 10, nil),
+		},
+		CacheKey: "a",
+	}
+	lb.Rebalance(e)
+	for i := 0; i < 10; i++ {
+		lb.Pick(e)
+	}
+	lb.Rebalance(discovery.Result{CacheKey: "b"})
+	lb.Pick(discovery.Result{CacheKey: "b"})
+	lb.Delete("b")
+
+	rm, err := reader.Collect(context.Background())
+	assert.Nil(t, err)
+	got := make(map[string]metricdata.Aggregation)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			got[m.Name] = m.Data
+		}
+	}
+
+	picks := got["loadbalance.picks"].(metricdata.Sum[int64])
+	assert.DeepEqual(t, 2, len(picks.DataPoints))
+	for _, dp := range picks.DataPoints {
+		assert.DeepEqual(t, int64(5), dp.Value)
+	}
+	failures := got["loadbalance.pick_failures"].(metricdata.Sum[int64])
+	assert.DeepEqual(t, int64(1), failures.DataPoints[0].Value)
+	reason, _ := failures.DataPoints[0].Attributes.Value(reasonKey)
+	assert.DeepEqual(t, loadbalanceEx.FailureNoInstances, reason.AsString())
+	duration := got["loadbalance.pick.duration"].(metricdata.Histogram)
+	var count uint64
+	for _, dp := range duration.DataPoints {
+		count += dp.Count
+	}
+	assert.DeepEqual(t, uint64(11), count)
+	rebalances := got["loadbalance.rebalances"].(metricdata.Sum[int64])
+	assert.DeepEqual(t, 2, len(rebalances.DataPoints))
+	deletes := got["loadbalance.deletes"].(metricdata.Sum[int64])
+	assert.DeepEqual(t, int64(1), deletes.DataPoints[0].Value)
+}
+
+func TestAnnotateSpan(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	ins := discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil)
+
+	// no span in context
+	AnnotateSpan(context.Background(), "round_robin", ins)
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "request")
+	AnnotateSpan(ctx, "round_robin", nil)
+	AnnotateSpan(ctx, "round_robin", ins)
+	span.End()
+
+	spans := sr.Ended()
+	assert.DeepEqual(t, 1, len(spans))
+	attrs := attribute.NewSet(spans[0].Attributes()...)
+	v, ok := attrs.Value(balancerKey)
+	assert.True(t, ok)
+	assert.DeepEqual(t, "round_robin", v.AsString())
+	v, ok = attrs.Value(addressKey)
+	assert.True(t, ok)
+	assert.DeepEqual(t, "127.0.0.1:8880", v.AsString())
+}
+
+func TestAnnotateRequest(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "request")
+	// requests without service discovery are not annotated
+	req := protocol.AcquireRequest()
+	defer protocol.ReleaseRequest(req)
+	req.SetHost("example.com")
+	AnnotateRequest(ctx, "round_robin", req)
+	span.End()
+	assert.DeepEqual(t, 0, len(sr.Ended()[0].Attributes()))
+
+	ctx, span = tp.Tracer("test").Start(context.Background(), "request")
+	req.SetOptions(hertzconfig.WithSD(true))
+	req.SetHost("127.0.0.1:8880")
+	AnnotateRequest(ctx, "round_robin", req)
+	span.End()
+
+	attrs := attribute.NewSet(sr.Ended()[1].Attributes()...)
+	v, ok := attrs.Value(balancerKey)
+	assert.True(t, ok)
+	assert.DeepEqual(t, "round_robin", v.AsString())
+	v, ok = attrs.Value(addressKey)
+	assert.True(t, ok)
+	assert.DeepEqual(t, "127.0.0.1:8880", v.AsString())
+}
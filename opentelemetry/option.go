@@ -0,0 +1,66 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package opentelemetry
+
+import (
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/global"
+)
+
+// Option is the only struct that can be used to set config.
+type Option interface {
+	apply(cfg *config)
+}
+
+type option func(cfg *config)
+
+func (fn option) apply(cfg *config) {
+	fn(cfg)
+}
+
+type config struct {
+	meterProvider metric.MeterProvider
+	labels        loadbalanceEx.MetricLabels
+}
+
+func newConfig(opts []Option) *config {
+	cfg := &config{
+		meterProvider: global.MeterProvider(),
+		labels:        loadbalanceEx.DefaultMetricLabels,
+	}
+	for _, opt := range opts {
+		opt.apply(cfg)
+	}
+	return cfg
+}
+
+// WithMeterProvider sets the meter provider the instruments are created from,
+// the global meter provider is used by default.
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return option(func(cfg *config) {
+		cfg.meterProvider = mp
+	})
+}
+
+// WithLabels sets the attributes of the instruments, loadbalance.DefaultMetricLabels by default.
+// Label names are prefixed with "loadbalance.".
+func WithLabels(labels loadbalanceEx.MetricLabels) Option {
+	return option(func(cfg *config) {
+		cfg.labels = labels
+	})
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package opentelemetry
+
+import (
+	"context"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/protocol"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// AnnotateSpan sets the name of the balancer and the address of the picked instance
+// on the span of ctx. It does nothing if ctx carries no recording span or ins is nil.
+func AnnotateSpan(ctx context.Context, balancerName string, ins discovery.Instance) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() || ins == nil || ins.Address() == nil {
+		return
+	}
+	span.SetAttributes(
+		balancerKey.String(balancerName),
+		addressKey.String(ins.Address().String()),
+	)
+}
+
+// AnnotateRequest sets the name of the balancer and the address picked by the service discovery
+// middleware for req on the span of ctx. It is meant to be called by a client middleware used
+// after sd.Discovery, and does nothing if req does not use service discovery.
+func AnnotateRequest(ctx context.Context, balancerName string, req *protocol.Request) {
+	if req.Options() == nil || !req.Options().IsSD() {
+		return
+	}
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	span.SetAttributes(
+		balancerKey.String(balancerName),
+		addressKey.String(string(req.Host())),
+	)
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance
+
+import (
+	"context"
+
+	"github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/protocol"
+)
+
+type (
+	forcedAddressKey struct{}
+	forcedTagsKey    struct{}
+)
+
+// WithForcedAddress returns a copy of ctx forcing the requests made with it to the instance at addr,
+// bypassing service discovery and load balancing, for debugging and admin traffic. See Override.
+func WithForcedAddress(ctx context.Context, addr string) context.Context {
+	return context.WithValue(ctx, forcedAddressKey{}, addr)
+}
+
+// ForcedAddress returns the address forced by ctx.
+func ForcedAddress(ctx context.Context) (string, bool) {
+	addr, ok := ctx.Value(forcedAddressKey{}).(string)
+	return addr, ok
+}
+
+// WithForcedTags returns a copy of ctx setting tags on the requests made with it, e.g. a zone,
+// so that the resolver targets the instances matching them. See Override.
+func WithForcedTags(ctx context.Context, tags map[string]string) context.Context {
+	return context.WithValue(ctx, forcedTagsKey{}, tags)
+}
+
+// ForcedTags returns the tags forced by ctx.
+func ForcedTags(ctx context.Context) (map[string]string, bool) {
+	tags, ok := ctx.Value(forcedTagsKey{}).(map[string]string)
+	return tags, ok
+}
+
+// Override applies the overrides of ctx to req. It is meant to be called by a client middleware used
+// before sd.Discovery: a forced address disables service discovery for req and sends it to the address,
+// forced tags are set on req so that the resolver targets the instances matching them.
+func Override(ctx context.Context, req *protocol.Request) {
+	if !req.Options().IsSD() {
+		return
+	}
+	if addr, ok := ForcedAddress(ctx); ok {
+		req.SetOptions(config.WithSD(false))
+		req.SetHost(addr)
+		return
+	}
+	if tags, ok := ForcedTags(ctx); ok {
+		opts := make([]config.RequestOption, 0, len(tags))
+		for k, v := range tags {
+			opts = append(opts, config.WithTag(k, v))
+		}
+		req.SetOptions(opts...)
+	}
+}
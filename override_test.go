@@ -0,0 +1,61 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	"github.com/cloudwego/hertz/pkg/protocol"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+)
+
+func TestOverride(t *testing.T) {
+	req := protocol.AcquireRequest()
+	defer protocol.ReleaseRequest(req)
+	req.SetOptions(config.WithSD(true))
+	req.SetHost("hertz.test.demo")
+
+	// nothing forced
+	loadbalanceEx.Override(context.Background(), req)
+	assert.True(t, req.Options().IsSD())
+
+	ctx := loadbalanceEx.WithForcedTags(context.Background(), map[string]string{"zone": "a"})
+	tags, ok := loadbalanceEx.ForcedTags(ctx)
+	assert.True(t, ok)
+	assert.DeepEqual(t, "a", tags["zone"])
+	loadbalanceEx.Override(ctx, req)
+	assert.True(t, req.Options().IsSD())
+	assert.DeepEqual(t, "a", req.Options().Tag("zone"))
+
+	ctx = loadbalanceEx.WithForcedAddress(ctx, "127.0.0.1:8880")
+	addr, ok := loadbalanceEx.ForcedAddress(ctx)
+	assert.True(t, ok)
+	assert.DeepEqual(t, "127.0.0.1:8880", addr)
+	loadbalanceEx.Override(ctx, req)
+	assert.False(t, req.Options().IsSD())
+	assert.DeepEqual(t, "127.0.0.1:8880", string(req.Host()))
+
+	// requests without service discovery are left untouched
+	other := protocol.AcquireRequest()
+	defer protocol.ReleaseRequest(other)
+	other.SetHost("example.com")
+	loadbalanceEx.Override(ctx, other)
+	assert.DeepEqual(t, "example.com", string(other.Host()))
+}
@@ -0,0 +1,42 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance
+
+import (
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+)
+
+// Primer is implemented by balancers able to compute the state of a cache key ahead of its first pick.
+type Primer interface {
+	// Prime computes and caches the state of e, unless it is already cached.
+	Prime(e discovery.Result)
+}
+
+// Prime computes the state of e in lb, or in the balancer it wraps, before traffic starts, so that the
+// first picks of e do not wait for it. Unlike a rebalance it emits no event and keeps a cached state.
+// Balancers which do not implement Primer are rebalanced instead.
+func Prime(lb loadbalance.Loadbalancer, e discovery.Result) {
+	if p, ok := find(lb, func(lb loadbalance.Loadbalancer) bool {
+		_, ok := lb.(Primer)
+		return ok
+	}).(Primer); ok {
+		p.Prime(e)
+		return
+	}
+	lb.Rebalance(e)
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance_test
+
+import (
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+	roundrobin "github.com/hertz-contrib/loadbalance/round_robin"
+)
+
+// rebalanceCounter counts its rebalances.
+type rebalanceCounter struct {
+	firstBalancer
+	rebalances int
+}
+
+func (c *rebalanceCounter) Rebalance(discovery.Result) { c.rebalances++ }
+
+func TestPrime(t *testing.T) {
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 10, nil),
+		},
+		CacheKey: "a",
+	}
+
+	lb := roundrobin.NewRoundRobinBalancer()
+	n := loadbalanceEx.NewNotifier(lb)
+	rebalances := 0
+	n.AddListener(loadbalanceEx.SynthesizedListener{RebalanceFunc: func(loadbalanceEx.RebalanceEvent) { rebalances++ }})
+	loadbalanceEx.Prime(n, e)
+	assert.DeepEqual(t, 0, rebalances)
+	assert.DeepEqual(t, "127.0.0.1:8880", n.Pick(e).Address().String())
+	// a cached state is kept
+	loadbalanceEx.Prime(n, e)
+	assert.DeepEqual(t, "127.0.0.1:8881", n.Pick(e).Address().String())
+	stats, _ := loadbalanceEx.CacheStatsOf(n)
+	assert.DeepEqual(t, loadbalanceEx.CacheStats{Hits: 2, Computes: 1, Entries: 1}, stats)
+
+	// balancers without Prime are rebalanced
+	c := &rebalanceCounter{}
+	loadbalanceEx.Prime(c, e)
+	assert.DeepEqual(t, 1, c.rebalances)
+
+	// composite balancers prime the balancers they delegate to
+	routed, fallback := roundrobin.NewRoundRobinBalancer(), roundrobin.NewRoundRobinBalancer()
+	router := loadbalanceEx.NewRouter(map[string]loadbalance.Loadbalancer{"a": routed}, fallback)
+	loadbalanceEx.Prime(loadbalanceEx.NewFallback(router, c), e)
+	_, ok := loadbalanceEx.Snapshot(routed, "a")
+	assert.True(t, ok)
+	_, ok = loadbalanceEx.Snapshot(fallback, "a")
+	assert.False(t, ok)
+	assert.DeepEqual(t, 2, c.rebalances)
+}
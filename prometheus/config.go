@@ -0,0 +1,69 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package prometheus
+
+import (
+	"fmt"
+
+	"github.com/prometheus/common/model"
+)
+
+// Config is the declarative configuration of the prometheus balancer,
+// zero fields take their default value.
+type Config struct {
+	// Namespace is the namespace of the metrics, "hertz" by default.
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	// Buckets are the buckets of the pick duration histogram in seconds, in increasing order.
+	Buckets []float64 `json:"buckets,omitempty" yaml:"buckets,omitempty"`
+}
+
+// Validate returns an error if the configuration is invalid.
+func (c Config) Validate() error {
+	if c.Namespace != "" && !model.IsValidMetricName(model.LabelValue(c.Namespace)) {
+		return fmt.Errorf("prometheus: invalid namespace %q", c.Namespace)
+	}
+	for i := 1; i < len(c.Buckets); i++ {
+		if c.Buckets[i] <= c.Buckets[i-1] {
+			return fmt.Errorf("prometheus: buckets are not in increasing order at %v", c.Buckets[i])
+		}
+	}
+	return nil
+}
+
+// WithDefaults returns the configuration with the default value of its zero fields.
+func (c Config) WithDefaults() Config {
+	def := newConfig(nil)
+	if c.Namespace == "" {
+		c.Namespace = def.namespace
+	}
+	if len(c.Buckets) == 0 {
+		c.Buckets = append([]float64(nil), def.buckets...)
+	}
+	return c
+}
+
+// Options returns the options equivalent to the configuration.
+func (c Config) Options() []Option {
+	var opts []Option
+	if c.Namespace != "" {
+		opts = append(opts, WithNamespace(c.Namespace))
+	}
+	if len(c.Buckets) > 0 {
+		opts = append(opts, WithBuckets(c.Buckets))
+	}
+	return opts
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package prometheus
+
+import (
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// Option is the only struct that can be used to set config.
+type Option interface {
+	apply(cfg *config)
+}
+
+type option func(cfg *config)
+
+func (fn option) apply(cfg *config) {
+	fn(cfg)
+}
+
+// defaultBuckets covers picks from a microsecond, the usual cost of a cached pick,
+// up to ten milliseconds for cache rebuilds of large instance lists.
+var defaultBuckets = []float64{
+	0.000001, 0.0000025, 0.000005, 0.00001, 0.000025, 0.00005,
+	0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01,
+}
+
+type config struct {
+	registerer prom.Registerer
+	namespace  string
+	buckets    []float64
+	labels     loadbalanceEx.MetricLabels
+}
+
+func newConfig(opts []Option) *config {
+	cfg := &config{
+		registerer: prom.DefaultRegisterer,
+		namespace:  "hertz",
+		buckets:    defaultBuckets,
+		labels:     loadbalanceEx.DefaultMetricLabels,
+	}
+	for _, opt := range opts {
+		opt.apply(cfg)
+	}
+	return cfg
+}
+
+// WithRegisterer sets the registerer the collectors are registered to,
+// prometheus.DefaultRegisterer is used by default.
+func WithRegisterer(registerer prom.Registerer) Option {
+	return option(func(cfg *config) {
+		cfg.registerer = registerer
+	})
+}
+
+// WithNamespace sets the namespace of the exported metrics, "hertz" by default.
+func WithNamespace(namespace string) Option {
+	return option(func(cfg *config) {
+		cfg.namespace = namespace
+	})
+}
+
+// WithBuckets sets the buckets of the pick duration histogram in seconds.
+func WithBuckets(buckets []float64) Option {
+	return option(func(cfg *config) {
+		cfg.buckets = buckets
+	})
+}
+
+// WithLabels sets the labels of the metrics, loadbalance.DefaultMetricLabels by default.
+// Balancers sharing a registerer must use the same label names.
+func WithLabels(labels loadbalanceEx.MetricLabels) Option {
+	return option(func(cfg *config) {
+		cfg.labels = labels
+	})
+}
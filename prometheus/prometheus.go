@@ -0,0 +1,210 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package prometheus
+
+import (
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	labelBalancer = "balancer"
+	labelReason   = "reason"
+)
+
+type metrics struct {
+	picks        *prom.CounterVec
+	pickFailures *prom.CounterVec
+	pickDuration *prom.HistogramVec
+	rebalances   *prom.CounterVec
+	deletes      *prom.CounterVec
+	instances    *prom.GaugeVec
+	weights      *prom.GaugeVec
+}
+
+type balancer struct {
+	lb      loadbalance.Loadbalancer
+	labels  loadbalanceEx.MetricLabels
+	metrics *metrics
+}
+
+// NewBalancer wraps lb so that its picks, rebalances and deletes are exported as prometheus metrics.
+func NewBalancer(lb loadbalance.Loadbalancer, opts ...Option) loadbalance.Loadbalancer {
+	cfg := newConfig(opts)
+	return &balancer{
+		lb:      lb,
+		labels:  cfg.labels,
+		metrics: newMetrics(cfg),
+	}
+}
+
+func newMetrics(cfg *config) *metrics {
+	keyLabels := append([]string{labelBalancer}, cfg.labels.Names...)
+	instanceLabels := append(append([]string(nil), keyLabels...), cfg.labels.InstanceNames...)
+	failureLabels := append(append([]string(nil), keyLabels...), labelReason)
+	return &metrics{
+		picks: registerCounterVec(cfg.registerer, prom.NewCounterVec(prom.CounterOpts{
+			Namespace: cfg.namespace,
+			Subsystem: "loadbalance",
+			Name:      "picks_total",
+			Help:      "Total number of times an instance was picked.",
+		}, instanceLabels)),
+		pickFailures: registerCounterVec(cfg.registerer, prom.NewCounterVec(prom.CounterOpts{
+			Namespace: cfg.namespace,
+			Subsystem: "loadbalance",
+			Name:      "pick_failures_total",
+			Help:      "Total number of picks that returned no instance, by reason.",
+		}, failureLabels)),
+		pickDuration: registerHistogramVec(cfg.registerer, prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace: cfg.namespace,
+			Subsystem: "loadbalance",
+			Name:      "pick_duration_seconds",
+			Help:      "Time spent inside Pick, including cache misses.",
+			Buckets:   cfg.buckets,
+		}, keyLabels)),
+		rebalances: registerCounterVec(cfg.registerer, prom.NewCounterVec(prom.CounterOpts{
+			Namespace: cfg.namespace,
+			Subsystem: "loadbalance",
+			Name:      "rebalances_total",
+			Help:      "Total number of rebalances.",
+		}, keyLabels)),
+		deletes: registerCounterVec(cfg.registerer, prom.NewCounterVec(prom.CounterOpts{
+			Namespace: cfg.namespace,
+			Subsystem: "loadbalance",
+			Name:      "deletes_total",
+			Help:      "Total number of deleted cache entries.",
+		}, keyLabels)),
+		instances: registerGaugeVec(cfg.registerer, prom.NewGaugeVec(prom.GaugeOpts{
+			Namespace: cfg.namespace,
+			Subsystem: "loadbalance",
+			Name:      "instances",
+			Help:      "Number of instances of the latest rebalance.",
+		}, keyLabels)),
+		weights: registerGaugeVec(cfg.registerer, prom.NewGaugeVec(prom.GaugeOpts{
+			Namespace: cfg.namespace,
+			Subsystem: "loadbalance",
+			Name:      "instance_weight",
+			Help:      "Configured weight of the instances of the latest rebalance, summed by instance labels.",
+		}, instanceLabels)),
+	}
+}
+
+// registerCounterVec registers c, or returns the collector already registered with the same descriptor,
+// so that several balancers can share one registerer.
+func registerCounterVec(registerer prom.Registerer, c *prom.CounterVec) *prom.CounterVec {
+	if err := registerer.Register(c); err != nil {
+		if are, ok := err.(prom.AlreadyRegisteredError); ok {
+			return are.ExistingCollector.(*prom.CounterVec)
+		}
+		panic(err)
+	}
+	return c
+}
+
+// registerGaugeVec is the same as registerCounterVec for gauges.
+func registerGaugeVec(registerer prom.Registerer, g *prom.GaugeVec) *prom.GaugeVec {
+	if err := registerer.Register(g); err != nil {
+		if are, ok := err.(prom.AlreadyRegisteredError); ok {
+			return are.ExistingCollector.(*prom.GaugeVec)
+		}
+		panic(err)
+	}
+	return g
+}
+
+// registerHistogramVec is the same as registerCounterVec for histograms.
+func registerHistogramVec(registerer prom.Registerer, h *prom.HistogramVec) *prom.HistogramVec {
+	if err := registerer.Register(h); err != nil {
+		if are, ok := err.(prom.AlreadyRegisteredError); ok {
+			return are.ExistingCollector.(*prom.HistogramVec)
+		}
+		panic(err)
+	}
+	return h
+}
+
+// Pick implements the Loadbalancer interface.
+func (b *balancer) Pick(e discovery.Result) discovery.Instance {
+	start := time.Now()
+	ins := b.lb.Pick(e)
+	keyValues := b.keyValues(e.CacheKey)
+	b.metrics.pickDuration.WithLabelValues(keyValues...).Observe(time.Since(start).Seconds())
+	if ins == nil {
+		b.metrics.pickFailures.WithLabelValues(append(keyValues, loadbalanceEx.FailureReason(b.lb, e))...).Inc()
+		return nil
+	}
+	b.metrics.picks.WithLabelValues(append(keyValues, b.labels.InstanceValuesOf(ins)...)...).Inc()
+	return ins
+}
+
+// Rebalance implements the Loadbalancer interface.
+func (b *balancer) Rebalance(e discovery.Result) {
+	b.lb.Rebalance(e)
+
+	// the instances with a malformed address are never picked
+	instances, _ := loadbalanceEx.SanitizeInstances(e.Instances)
+	keyValues := b.keyValues(e.CacheKey)
+	b.metrics.rebalances.WithLabelValues(keyValues...).Inc()
+	b.metrics.instances.WithLabelValues(keyValues...).Set(float64(len(instances)))
+	b.metrics.weights.DeletePartialMatch(b.keyLabels(e.CacheKey))
+	for _, ins := range instances {
+		b.metrics.weights.WithLabelValues(append(keyValues, b.labels.InstanceValuesOf(ins)...)...).Add(float64(ins.Weight()))
+	}
+}
+
+// Delete implements the Loadbalancer interface.
+func (b *balancer) Delete(cacheKey string) {
+	b.lb.Delete(cacheKey)
+
+	keyValues := b.keyValues(cacheKey)
+	b.metrics.deletes.WithLabelValues(keyValues...).Inc()
+	b.metrics.instances.DeleteLabelValues(keyValues...)
+	b.metrics.pickDuration.DeleteLabelValues(keyValues...)
+	b.metrics.weights.DeletePartialMatch(b.keyLabels(cacheKey))
+	b.metrics.picks.DeletePartialMatch(b.keyLabels(cacheKey))
+	b.metrics.pickFailures.DeletePartialMatch(b.keyLabels(cacheKey))
+}
+
+// keyValues returns the values of the labels of the metrics of cacheKey.
+// The slice has spare capacity for the instance labels, or the failure reason.
+func (b *balancer) keyValues(cacheKey string) []string {
+	values := make([]string, 0, 2+len(b.labels.Names)+len(b.labels.InstanceNames))
+	values = append(values, b.lb.Name())
+	return append(values, b.labels.ValuesOf(cacheKey)...)
+}
+
+func (b *balancer) keyLabels(cacheKey string) prom.Labels {
+	labels := prom.Labels{labelBalancer: b.lb.Name()}
+	for i, value := range b.labels.ValuesOf(cacheKey) {
+		labels[b.labels.Names[i]] = value
+	}
+	return labels
+}
+
+// Name implements the Loadbalancer interface.
+func (b *balancer) Name() string {
+	return b.lb.Name()
+}
+
+// Unwrap returns the wrapped Loadbalancer.
+func (b *balancer) Unwrap() loadbalance.Loadbalancer {
+	return b.lb
+}
@@ -0,0 +1,166 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package prometheus
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+	roundrobin "github.com/hertz-contrib/loadbalance/round_robin"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestBalancer(t *testing.T) {
+	registry := prom.NewRegistry()
+	lb := NewBalancer(roundrobin.NewRoundRobinBalancer(), WithRegisterer(registry))
+	assert.DeepEqual(t, "round_robin", lb.Name())
+
+	m := lb.(*balancer).metrics
+
+	// empty instance
+	e := discovery.Result{
+		Instances: make([]discovery.Instance, 0),
+		CacheKey:  "a",
+	}
+	lb.Rebalance(e)
+	assert.Nil(t, lb.Pick(e))
+	assert.DeepEqual(t, float64(1), testutil.ToFloat64(m.pickFailures.WithLabelValues("round_robin", "a", "no_instances")))
+
+	// multi instances
+	e = discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 20, nil),
+		},
+		CacheKey: "b",
+	}
+	lb.Rebalance(e)
+	for i := 0; i < 10; i++ {
+		lb.Pick(e)
+	}
+	assert.DeepEqual(t, float64(1), testutil.ToFloat64(m.rebalances.WithLabelValues("round_robin", "b")))
+	assert.DeepEqual(t, float64(2), testutil.ToFloat64(m.instances.WithLabelValues("round_robin", "b")))
+	assert.DeepEqual(t, float64(20), testutil.ToFloat64(m.weights.WithLabelValues("round_robin", "b", "127.0.0.1:8881")))
+	assert.DeepEqual(t, float64(5), testutil.ToFloat64(m.picks.WithLabelValues("round_robin", "b", "127.0.0.1:8880")))
+	assert.DeepEqual(t, float64(5), testutil.ToFloat64(m.picks.WithLabelValues("round_robin", "b", "127.0.0.1:8881")))
+	assert.DeepEqual(t, 2, testutil.CollectAndCount(m.pickDuration))
+
+	lb.Delete("b")
+	assert.DeepEqual(t, float64(1), testutil.ToFloat64(m.deletes.WithLabelValues("round_robin", "b")))
+	assert.DeepEqual(t, 0, testutil.CollectAndCount(m.weights))
+
+	// malformed instances are left out
+	lb.Rebalance(discovery.Result{
+		Instances: []discovery.Instance{nil, discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil)},
+		CacheKey:  "c",
+	})
+	assert.DeepEqual(t, float64(1), testutil.ToFloat64(m.instances.WithLabelValues("round_robin", "c")))
+	assert.DeepEqual(t, 1, testutil.CollectAndCount(m.weights))
+
+	// collectors are shared between balancers using the same registerer
+	other := NewBalancer(roundrobin.NewRoundRobinBalancer(), WithRegisterer(registry))
+	assert.Assert(t, other.(*balancer).metrics.picks == m.picks)
+}
+
+func TestLabels(t *testing.T) {
+	registry := prom.NewRegistry()
+	lb := NewBalancer(roundrobin.NewRoundRobinBalancer(), WithRegisterer(registry), WithLabels(loadbalanceEx.MetricLabels{
+		Names: []string{"service"},
+		Values: func(cacheKey string) []string {
+			return []string{strings.TrimPrefix(cacheKey, "nacos:")}
+		},
+		InstanceNames: []string{"zone"},
+		InstanceValues: func(ins discovery.Instance) []string {
+			zone, _ := ins.Tag("zone")
+			return []string{zone}
+		},
+	}))
+	m := lb.(*balancer).metrics
+
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, map[string]string{"zone": "a"}),
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 10, map[string]string{"zone": "a"}),
+			discovery.NewInstance("tcp", "127.0.0.1:8882", 10, map[string]string{"zone": "b"}),
+		},
+		CacheKey: "nacos:svc",
+	}
+	lb.Rebalance(e)
+	for i := 0; i < 6; i++ {
+		lb.Pick(e)
+	}
+	assert.DeepEqual(t, float64(4), testutil.ToFloat64(m.picks.WithLabelValues("round_robin", "svc", "a")))
+	assert.DeepEqual(t, float64(2), testutil.ToFloat64(m.picks.WithLabelValues("round_robin", "svc", "b")))
+	assert.DeepEqual(t, 2, testutil.CollectAndCount(m.picks))
+	assert.DeepEqual(t, float64(1), testutil.ToFloat64(m.rebalances.WithLabelValues("round_robin", "svc")))
+
+	lb.Delete("nacos:svc")
+	assert.DeepEqual(t, 0, testutil.CollectAndCount(m.picks))
+}
+
+func TestPush(t *testing.T) {
+	var body string
+	var path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	registry := prom.NewRegistry()
+	registry.MustRegister(prom.NewCounter(prom.CounterOpts{Name: "other_total"}))
+	lb := NewBalancer(roundrobin.NewRoundRobinBalancer(), WithRegisterer(registry))
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+		},
+		CacheKey: "a",
+	}
+	lb.Rebalance(e)
+	lb.Pick(e)
+
+	assert.Nil(t, Push(context.Background(), srv.URL, "batch", WithRegisterer(registry)))
+	assert.DeepEqual(t, "/metrics/job/batch", path)
+	assert.Assert(t, strings.Contains(body, "hertz_loadbalance_picks_total"))
+	assert.False(t, strings.Contains(body, "other_total"))
+
+	assert.NotNil(t, Push(context.Background(), srv.URL, "batch", WithRegisterer(struct{ prom.Registerer }{registry})))
+}
+
+func TestConfig(t *testing.T) {
+	assert.Nil(t, Config{}.Validate())
+	assert.NotNil(t, Config{Namespace: "my-app"}.Validate())
+	assert.NotNil(t, Config{Buckets: []float64{0.1, 0.1}}.Validate())
+
+	cfg := Config{Namespace: "app", Buckets: []float64{0.001, 0.01}}
+	assert.Nil(t, cfg.Validate())
+	assert.DeepEqual(t, cfg, cfg.WithDefaults())
+	assert.DeepEqual(t, Config{Namespace: "hertz", Buckets: defaultBuckets}, Config{}.WithDefaults())
+	c := newConfig(cfg.Options())
+	assert.DeepEqual(t, "app", c.namespace)
+	assert.DeepEqual(t, []float64{0.001, 0.01}, c.buckets)
+}
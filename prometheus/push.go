@@ -0,0 +1,61 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Push pushes the load balancer metrics to the Pushgateway at url, grouped under job,
+// replacing the metrics previously pushed for the job. Short-lived processes such as
+// CLI tools and batch jobs, which exit before being scraped, call it before exiting.
+// It takes the same options as NewBalancer, the registerer must also be a prometheus.Gatherer.
+func Push(ctx context.Context, url, job string, opts ...Option) error {
+	cfg := newConfig(opts)
+	g, ok := cfg.registerer.(prom.Gatherer)
+	if !ok {
+		return errors.New("prometheus: the registerer is not a gatherer")
+	}
+	prefix := "loadbalance_"
+	if cfg.namespace != "" {
+		prefix = cfg.namespace + "_" + prefix
+	}
+	return push.New(url, job).Gatherer(&filteredGatherer{Gatherer: g, prefix: prefix}).PushContext(ctx)
+}
+
+// filteredGatherer only gathers the metric families whose name starts with prefix.
+type filteredGatherer struct {
+	prom.Gatherer
+	prefix string
+}
+
+func (fg *filteredGatherer) Gather() ([]*dto.MetricFamily, error) {
+	mfs, err := fg.Gatherer.Gather()
+	res := mfs[:0]
+	for _, mf := range mfs {
+		if strings.HasPrefix(mf.GetName(), fg.prefix) {
+			res = append(res, mf)
+		}
+	}
+	return res, err
+}
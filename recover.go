@@ -0,0 +1,105 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance
+
+import (
+	"math/rand"
+	"runtime/debug"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+)
+
+// PanicEvent describes a panic recovered by a Recoverer.
+type PanicEvent struct {
+	Balancer string
+	// Method is the method of the balancer which panicked: "Pick", "Rebalance" or "Delete".
+	Method   string
+	CacheKey string
+	// Value is the value passed to panic.
+	Value interface{}
+	Stack []byte
+}
+
+// Recoverer is a Loadbalancer containing the panics of the balancer it wraps, so that a bug in a custom
+// balancer degrades to a random pick instead of crashing the goroutine of the request.
+type Recoverer struct {
+	lb      loadbalance.Loadbalancer
+	onPanic func(PanicEvent)
+}
+
+// NewRecoverer wraps lb and recovers its panics, reported to onPanic if not nil.
+// Picks which panic return a random instance of the result.
+func NewRecoverer(lb loadbalance.Loadbalancer, onPanic func(PanicEvent)) *Recoverer {
+	return &Recoverer{lb: lb, onPanic: onPanic}
+}
+
+// Pick implements the Loadbalancer interface.
+func (r *Recoverer) Pick(e discovery.Result) (ins discovery.Instance) {
+	defer func() {
+		if v := recover(); v != nil {
+			r.report("Pick", e.CacheKey, v)
+			ins = nil
+			if len(e.Instances) > 0 {
+				ins = e.Instances[rand.Intn(len(e.Instances))]
+			}
+		}
+	}()
+	return r.lb.Pick(e)
+}
+
+// Rebalance implements the Loadbalancer interface.
+func (r *Recoverer) Rebalance(e discovery.Result) {
+	defer func() {
+		if v := recover(); v != nil {
+			r.report("Rebalance", e.CacheKey, v)
+		}
+	}()
+	r.lb.Rebalance(e)
+}
+
+// Delete implements the Loadbalancer interface.
+func (r *Recoverer) Delete(cacheKey string) {
+	defer func() {
+		if v := recover(); v != nil {
+			r.report("Delete", cacheKey, v)
+		}
+	}()
+	r.lb.Delete(cacheKey)
+}
+
+func (r *Recoverer) report(method, cacheKey string, v interface{}) {
+	if r.onPanic != nil {
+		r.onPanic(PanicEvent{
+			Balancer: r.lb.Name(),
+			Method:   method,
+			CacheKey: cacheKey,
+			Value:    v,
+			Stack:    debug.Stack(),
+		})
+	}
+}
+
+// Name implements the Loadbalancer interface.
+func (r *Recoverer) Name() string {
+	return r.lb.Name()
+}
+
+// Unwrap implements the Wrapper interface.
+func (r *Recoverer) Unwrap() loadbalance.Loadbalancer {
+	return r.lb
+}
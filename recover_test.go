@@ -0,0 +1,71 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance_test
+
+import (
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+)
+
+// panicBalancer panics on every call.
+type panicBalancer struct {
+	firstBalancer
+}
+
+func (panicBalancer) Pick(discovery.Result) discovery.Instance { panic("pick") }
+
+func (panicBalancer) Rebalance(discovery.Result) { panic("rebalance") }
+
+func (panicBalancer) Delete(string) { panic("delete") }
+
+func TestRecoverer(t *testing.T) {
+	var events []loadbalanceEx.PanicEvent
+	lb := loadbalanceEx.NewRecoverer(panicBalancer{}, func(e loadbalanceEx.PanicEvent) {
+		events = append(events, e)
+	})
+	assert.DeepEqual(t, "first", lb.Name())
+
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 10, nil),
+		},
+		CacheKey: "a",
+	}
+	lb.Rebalance(e)
+	// picks degrade to a random instance
+	assert.NotNil(t, lb.Pick(e))
+	assert.Nil(t, lb.Pick(discovery.Result{CacheKey: "b"}))
+	lb.Delete("a")
+
+	assert.DeepEqual(t, 4, len(events))
+	assert.DeepEqual(t, "Rebalance", events[0].Method)
+	assert.DeepEqual(t, "Pick", events[1].Method)
+	assert.DeepEqual(t, "pick", events[1].Value)
+	assert.DeepEqual(t, "first", events[1].Balancer)
+	assert.DeepEqual(t, "a", events[1].CacheKey)
+	assert.True(t, len(events[1].Stack) > 0)
+	assert.DeepEqual(t, "b", events[2].CacheKey)
+	assert.DeepEqual(t, "Delete", events[3].Method)
+
+	// balancers which do not panic are left alone
+	lb = loadbalanceEx.NewRecoverer(firstBalancer{}, nil)
+	assert.DeepEqual(t, "127.0.0.1:8880", lb.Pick(e).Address().String())
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+)
+
+// BuildFunc creates a Loadbalancer.
+type BuildFunc func() loadbalance.Loadbalancer
+
+var (
+	buildersMu sync.RWMutex
+	builders   = make(map[string]BuildFunc)
+)
+
+// Register makes a balancer available under name, so that it can be selected by name in configurations.
+// Like database/sql drivers, balancers register themselves in an init function.
+// It panics if builder is nil or if name is already registered.
+func Register(name string, builder BuildFunc) {
+	buildersMu.Lock()
+	defer buildersMu.Unlock()
+	if builder == nil {
+		panic("loadbalance: Register builder is nil")
+	}
+	if _, dup := builders[name]; dup {
+		panic(fmt.Sprintf("loadbalance: Register called twice for %q", name))
+	}
+	builders[name] = builder
+}
+
+// Get returns the builder registered under name.
+func Get(name string) (BuildFunc, bool) {
+	buildersMu.RLock()
+	defer buildersMu.RUnlock()
+	builder, ok := builders[name]
+	return builder, ok
+}
+
+// MustGet is the same as Get but panics if no builder is registered under name.
+func MustGet(name string) BuildFunc {
+	builder, ok := Get(name)
+	if !ok {
+		panic(fmt.Sprintf("loadbalance: unknown balancer %q (forgotten import?)", name))
+	}
+	return builder
+}
+
+// Registered returns the sorted names of the registered balancers.
+func Registered() []string {
+	buildersMu.RLock()
+	defer buildersMu.RUnlock()
+	names := make([]string, 0, len(builders))
+	for name := range builders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
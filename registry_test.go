@@ -0,0 +1,53 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance_test
+
+import (
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+	_ "github.com/hertz-contrib/loadbalance/round_robin"
+)
+
+func TestRegister(t *testing.T) {
+	// round_robin registers itself when imported
+	assert.DeepEqual(t, "round_robin", loadbalanceEx.MustGet("round_robin")().Name())
+
+	loadbalanceEx.Register("first", func() loadbalance.Loadbalancer {
+		return firstBalancer{}
+	})
+	builder, ok := loadbalanceEx.Get("first")
+	assert.True(t, ok)
+	assert.DeepEqual(t, "first", builder().Name())
+	assert.DeepEqual(t, []string{"first", "round_robin"}, loadbalanceEx.Registered())
+
+	assert.Panic(t, func() {
+		loadbalanceEx.Register("first", func() loadbalance.Loadbalancer {
+			return firstBalancer{}
+		})
+	})
+	assert.Panic(t, func() {
+		loadbalanceEx.Register("nil", nil)
+	})
+	_, ok = loadbalanceEx.Get("random")
+	assert.False(t, ok)
+	assert.Panic(t, func() {
+		loadbalanceEx.MustGet("random")
+	})
+}
@@ -0,0 +1,145 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+)
+
+// FlagProvider is an external feature flag system driving a Rollout.
+type FlagProvider interface {
+	// CandidateFraction returns the fraction of picks, between 0 and 1, served by the candidate balancer.
+	CandidateFraction() float64
+}
+
+// FlagProviderFunc is an adapter to use a function as a FlagProvider.
+type FlagProviderFunc func() float64
+
+// CandidateFraction implements the FlagProvider interface.
+func (f FlagProviderFunc) CandidateFraction() float64 {
+	return f()
+}
+
+// Rollout is a Loadbalancer serving a fraction of the picks with a candidate balancer and the other ones
+// with the primary balancer, to roll out a new algorithm gradually. The fraction is polled from a
+// FlagProvider, so setting it back to 0 rolls back within a poll interval.
+// Both balancers receive every rebalance and delete, so either can take over all the picks.
+type Rollout struct {
+	// accessed atomically, first for 64-bit alignment
+	picks    uint64
+	fraction uint64 // float64 bits
+
+	primary   loadbalance.Loadbalancer
+	candidate loadbalance.Loadbalancer
+	flags     FlagProvider
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// defaultRolloutInterval is the poll interval of a Rollout created with an interval which is not positive.
+const defaultRolloutInterval = 10 * time.Second
+
+// NewRollout creates a Rollout polling flags every interval, 10 seconds if it is not positive, until Close is called.
+func NewRollout(primary, candidate loadbalance.Loadbalancer, flags FlagProvider, interval time.Duration) *Rollout {
+	if interval <= 0 {
+		interval = defaultRolloutInterval
+	}
+	r := &Rollout{
+		primary:   primary,
+		candidate: candidate,
+		flags:     flags,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	r.poll()
+	go r.loop(interval)
+	return r
+}
+
+func (r *Rollout) loop(interval time.Duration) {
+	defer close(r.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.poll()
+		}
+	}
+}
+
+func (r *Rollout) poll() {
+	f := math.Min(math.Max(r.flags.CandidateFraction(), 0), 1)
+	atomic.StoreUint64(&r.fraction, math.Float64bits(f))
+}
+
+// Fraction returns the fraction of picks currently served by the candidate balancer.
+func (r *Rollout) Fraction() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&r.fraction))
+}
+
+// Close stops polling the FlagProvider.
+func (r *Rollout) Close() {
+	r.once.Do(func() {
+		close(r.stop)
+	})
+	<-r.done
+}
+
+// Pick implements the Loadbalancer interface.
+// Picks are spread evenly: with a fraction of 0.1, one pick out of ten goes to the candidate.
+func (r *Rollout) Pick(e discovery.Result) discovery.Instance {
+	// the candidate serves the picks where n*f reaches the next integer
+	n, f := atomic.AddUint64(&r.picks, 1), r.Fraction()
+	if math.Floor(float64(n)*f) != math.Floor(float64(n-1)*f) {
+		return r.candidate.Pick(e)
+	}
+	return r.primary.Pick(e)
+}
+
+// Rebalance implements the Loadbalancer interface.
+func (r *Rollout) Rebalance(e discovery.Result) {
+	r.primary.Rebalance(e)
+	r.candidate.Rebalance(e)
+}
+
+// Prime implements the Primer interface, it primes both the primary and the candidate balancers.
+func (r *Rollout) Prime(e discovery.Result) {
+	Prime(r.primary, e)
+	Prime(r.candidate, e)
+}
+
+// Delete implements the Loadbalancer interface.
+func (r *Rollout) Delete(cacheKey string) {
+	r.primary.Delete(cacheKey)
+	r.candidate.Delete(cacheKey)
+}
+
+// Name implements the Loadbalancer interface.
+func (r *Rollout) Name() string {
+	return "rollout"
+}
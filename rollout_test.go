@@ -0,0 +1,81 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance_test
+
+import (
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+	roundrobin "github.com/hertz-contrib/loadbalance/round_robin"
+)
+
+func TestRollout(t *testing.T) {
+	var fraction uint64 // float64 bits
+	flags := loadbalanceEx.FlagProviderFunc(func() float64 {
+		return math.Float64frombits(atomic.LoadUint64(&fraction))
+	})
+	atomic.StoreUint64(&fraction, math.Float64bits(0.1))
+	candidate := roundrobin.NewRoundRobinBalancer()
+	r := loadbalanceEx.NewRollout(firstBalancer{}, candidate, flags, 5*time.Millisecond)
+	defer r.Close()
+	assert.DeepEqual(t, 0.1, r.Fraction())
+
+	e := discovery.Result{
+		Instances: []discovery.Instance{discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil)},
+		CacheKey:  "a",
+	}
+	r.Rebalance(e)
+	for i := 0; i < 100; i++ {
+		r.Pick(e)
+	}
+	state, _ := loadbalanceEx.Snapshot(candidate, "a")
+	assert.DeepEqual(t, uint64(10), state.Instances[0].Picks)
+
+	// rolling back stops serving picks with the candidate
+	atomic.StoreUint64(&fraction, math.Float64bits(0))
+	for r.Fraction() != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	for i := 0; i < 100; i++ {
+		r.Pick(e)
+	}
+	state, _ = loadbalanceEx.Snapshot(candidate, "a")
+	assert.DeepEqual(t, uint64(10), state.Instances[0].Picks)
+
+	// out of range fractions are clamped
+	atomic.StoreUint64(&fraction, math.Float64bits(2))
+	for r.Fraction() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	r.Delete("a")
+	_, ok := loadbalanceEx.Snapshot(candidate, "a")
+	assert.False(t, ok)
+}
+
+func TestRolloutDefaultInterval(t *testing.T) {
+	r := loadbalanceEx.NewRollout(firstBalancer{}, firstBalancer{}, loadbalanceEx.FlagProviderFunc(func() float64 {
+		return 0.5
+	}), 0)
+	defer r.Close()
+	assert.DeepEqual(t, 0.5, r.Fraction())
+}
@@ -0,0 +1,227 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package roundrobin
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// cacheShards is the number of shards of a cache, a power of two.
+const cacheShards = 32
+
+// cache maps cache keys to their round-robin state. It is split into shards selected by the hash
+// of the cache key, so that processes balancing thousands of services do not contend on one lock.
+//
+// Every shard holds an immutable map, copied and swapped on writes, so that lookups never take a lock.
+// The round-robin states are immutable too but for their atomic index, picks are lock-free.
+type cache struct {
+	// number of entries, accessed atomically, first for 64-bit alignment
+	n      int64
+	shards [cacheShards]cacheShard
+}
+
+type cacheShard struct {
+	// number of deletes, accessed atomically, first for 64-bit alignment
+	deletes uint64
+	// serializes the writers
+	mu sync.Mutex
+	m  atomic.Value // map[string]*roundRobinInfo
+	// pads the shard to a 64-byte cache line on 64-bit platforms
+	_ [32]byte
+}
+
+// snapshot returns the current map of the shard, it must not be modified.
+func (s *cacheShard) snapshot() map[string]*roundRobinInfo {
+	m, _ := s.m.Load().(map[string]*roundRobinInfo)
+	return m
+}
+
+// copyWith stores a copy of the map of the shard modified by fn, s.mu must be held.
+func (s *cacheShard) copyWith(fn func(m map[string]*roundRobinInfo)) {
+	old := s.snapshot()
+	m := make(map[string]*roundRobinInfo, len(old)+1)
+	for key, r := range old {
+		m[key] = r
+	}
+	fn(m)
+	s.m.Store(m)
+}
+
+// fnv32a hashes key with FNV-1a, without allocating.
+func fnv32a(key string) uint32 {
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return h
+}
+
+func (c *cache) shard(key string) *cacheShard {
+	return &c.shards[fnv32a(key)&(cacheShards-1)]
+}
+
+func (c *cache) load(key string) (*roundRobinInfo, bool) {
+	r, ok := c.shard(key).snapshot()[key]
+	return r, ok
+}
+
+// store stores r as the state of key, it returns whether key was added.
+func (c *cache) store(key string, r *roundRobinInfo) bool {
+	s := c.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.snapshot()[key]
+	s.copyWith(func(m map[string]*roundRobinInfo) {
+		m[key] = r
+	})
+	if !ok {
+		atomic.AddInt64(&c.n, 1)
+	}
+	return !ok
+}
+
+// generation returns the generation of the shard of key, which changes whenever a key of the shard
+// is deleted. It is taken before computing a state to store with loadOrStoreSince. Evictions only
+// remove states which are present, so no state of them is being computed and they keep the generation.
+func (c *cache) generation(key string) uint64 {
+	return atomic.LoadUint64(&c.shard(key).deletes)
+}
+
+// loadOrStore returns the state of key if present, otherwise it stores and returns r.
+// It returns whether r was stored.
+func (c *cache) loadOrStore(key string, r *roundRobinInfo) (*roundRobinInfo, bool) {
+	return c.loadOrStoreSince(key, r, c.generation(key))
+}
+
+// loadOrStoreSince is loadOrStore, but r is not stored if a state of the shard of key was deleted
+// since gen, so that a delete racing with the computation of r is not undone. r is still returned.
+func (c *cache) loadOrStoreSince(key string, r *roundRobinInfo, gen uint64) (*roundRobinInfo, bool) {
+	s := c.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if old, ok := s.snapshot()[key]; ok {
+		return old, false
+	}
+	if atomic.LoadUint64(&s.deletes) != gen {
+		return r, false
+	}
+	s.copyWith(func(m map[string]*roundRobinInfo) {
+		m[key] = r
+	})
+	atomic.AddInt64(&c.n, 1)
+	return r, true
+}
+
+// delete deletes the state of key and returns it, or false if key is absent. It changes the generation
+// of the shard even if key is absent, since a state of key may be being computed.
+func (c *cache) delete(key string) (*roundRobinInfo, bool) {
+	s := c.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	atomic.AddUint64(&s.deletes, 1)
+	r, ok := s.snapshot()[key]
+	if !ok {
+		return nil, false
+	}
+	s.copyWith(func(m map[string]*roundRobinInfo) {
+		delete(m, key)
+	})
+	atomic.AddInt64(&c.n, -1)
+	return r, true
+}
+
+// compareAndSwap replaces the state of key by r if it is old, it returns whether it was replaced.
+func (c *cache) compareAndSwap(key string, old, r *roundRobinInfo) bool {
+	s := c.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.snapshot()[key] != old {
+		return false
+	}
+	s.copyWith(func(m map[string]*roundRobinInfo) {
+		m[key] = r
+	})
+	return true
+}
+
+// compareAndDelete deletes the state of key if it is r, it returns whether it was deleted.
+func (c *cache) compareAndDelete(key string, r *roundRobinInfo) bool {
+	s := c.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.snapshot()[key] != r {
+		return false
+	}
+	s.copyWith(func(m map[string]*roundRobinInfo) {
+		delete(m, key)
+	})
+	atomic.AddInt64(&c.n, -1)
+	return true
+}
+
+// deleteIf deletes the states for which fn returns true, it returns the deleted states by key.
+func (c *cache) deleteIf(fn func(r *roundRobinInfo) bool) map[string]*roundRobinInfo {
+	var deleted map[string]*roundRobinInfo
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.mu.Lock()
+		var keys []string
+		for key, r := range s.snapshot() {
+			if fn(r) {
+				keys = append(keys, key)
+				if deleted == nil {
+					deleted = make(map[string]*roundRobinInfo)
+				}
+				deleted[key] = r
+			}
+		}
+		if len(keys) > 0 {
+			s.copyWith(func(m map[string]*roundRobinInfo) {
+				for _, key := range keys {
+					delete(m, key)
+				}
+			})
+		}
+		s.mu.Unlock()
+	}
+	atomic.AddInt64(&c.n, -int64(len(deleted)))
+	return deleted
+}
+
+// rangeAll calls fn for every state of a snapshot of each shard until it returns false.
+func (c *cache) rangeAll(fn func(key string, r *roundRobinInfo) bool) {
+	c.rangeFrom(0, fn)
+}
+
+// rangeFrom is rangeAll starting from the shard start. Since the iteration order of maps is random,
+// the first states fn is called for are a cheap sample of the cache.
+func (c *cache) rangeFrom(start int, fn func(key string, r *roundRobinInfo) bool) {
+	for i := 0; i < cacheShards; i++ {
+		for key, r := range c.shards[(start+i)&(cacheShards-1)].snapshot() {
+			if !fn(key, r) {
+				return
+			}
+		}
+	}
+}
+
+// len returns the number of entries.
+func (c *cache) len() int {
+	return int(atomic.LoadInt64(&c.n))
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package roundrobin
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+)
+
+func TestCache(t *testing.T) {
+	var c cache
+	_, ok := c.load("a")
+	assert.False(t, ok)
+
+	a := newRoundRobinInfo(nil)
+	r, stored := c.loadOrStore("a", a)
+	assert.True(t, stored)
+	assert.True(t, a == r)
+	r, stored = c.loadOrStore("a", newRoundRobinInfo(nil))
+	assert.False(t, stored)
+	assert.True(t, a == r)
+	b := newRoundRobinInfo(nil)
+	assert.False(t, c.store("a", b))
+	r, ok = c.load("a")
+	assert.True(t, ok)
+	assert.True(t, b == r)
+	assert.False(t, c.compareAndDelete("a", a))
+	assert.True(t, c.compareAndDelete("a", b))
+	assert.True(t, c.store("a", a))
+	c.delete("a")
+	_, ok = c.load("a")
+	assert.False(t, ok)
+	assert.DeepEqual(t, 0, c.len())
+
+	// keys are spread over the shards
+	for i := 0; i < 1000; i++ {
+		c.store(strconv.Itoa(i), newRoundRobinInfo(nil))
+	}
+	for i := range c.shards {
+		assert.True(t, len(c.shards[i].snapshot()) > 0)
+	}
+	n := 0
+	c.rangeAll(func(key string, r *roundRobinInfo) bool {
+		n++
+		return true
+	})
+	assert.DeepEqual(t, 1000, n)
+	assert.DeepEqual(t, 1000, c.len())
+
+	// a delete during the computation of a state prevents storing it
+	gen := c.generation("b")
+	c.delete("b")
+	r, stored = c.loadOrStoreSince("b", a, gen)
+	assert.False(t, stored)
+	assert.True(t, a == r)
+	_, ok = c.load("b")
+	assert.False(t, ok)
+	_, stored = c.loadOrStoreSince("b", a, c.generation("b"))
+	assert.True(t, stored)
+	c.delete("b")
+
+	// writes do not modify the snapshots held by readers
+	snapshot := c.shard("0").snapshot()
+	c.delete("0")
+	_, ok = snapshot["0"]
+	assert.True(t, ok)
+	c.store("0", newRoundRobinInfo(nil))
+
+	assert.DeepEqual(t, 1000, len(c.deleteIf(func(r *roundRobinInfo) bool { return true })))
+	assert.DeepEqual(t, 0, c.len())
+	n = 0
+	c.rangeAll(func(key string, r *roundRobinInfo) bool {
+		n++
+		return true
+	})
+	assert.DeepEqual(t, 0, n)
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package roundrobin
+
+import (
+	"fmt"
+	"time"
+)
+
+// Config is the declarative configuration of the round-robin balancer,
+// zero fields take their default value.
+type Config struct {
+	// Name is the name of the balancer, "round_robin" by default.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// MaxIdle evicts the state of the cache keys which have not been picked for MaxIdle, disabled by default.
+	MaxIdle time.Duration `json:"max_idle,omitempty" yaml:"max_idle,omitempty"`
+	// MaxEntries bounds the number of cache keys whose state is held, evicting the least recently picked ones,
+	// unbounded by default.
+	MaxEntries int `json:"max_entries,omitempty" yaml:"max_entries,omitempty"`
+	// MaxAge recomputes the state of a cache key not rebalanced for MaxAge from the picked result,
+	// disabled by default.
+	MaxAge time.Duration `json:"max_age,omitempty" yaml:"max_age,omitempty"`
+	// DisableSingleflight computes the state of a cache key on every concurrent miss.
+	DisableSingleflight bool `json:"disable_singleflight,omitempty" yaml:"disable_singleflight,omitempty"`
+}
+
+// Validate returns an error if the configuration is invalid.
+func (c Config) Validate() error {
+	if c.MaxIdle < 0 {
+		return fmt.Errorf("roundrobin: negative max idle %v", c.MaxIdle)
+	}
+	if c.MaxAge < 0 {
+		return fmt.Errorf("roundrobin: negative max age %v", c.MaxAge)
+	}
+	if c.MaxEntries < 0 {
+		return fmt.Errorf("roundrobin: negative max entries %d", c.MaxEntries)
+	}
+	return nil
+}
+
+// WithDefaults returns the configuration with the default value of its zero fields.
+func (c Config) WithDefaults() Config {
+	if c.Name == "" {
+		c.Name = newConfig(nil).name
+	}
+	return c
+}
+
+// Options returns the options equivalent to the configuration.
+func (c Config) Options() []Option {
+	var opts []Option
+	if c.Name != "" {
+		opts = append(opts, WithName(c.Name))
+	}
+	if c.MaxIdle > 0 {
+		opts = append(opts, WithMaxIdle(c.MaxIdle))
+	}
+	if c.MaxEntries > 0 {
+		opts = append(opts, WithMaxEntries(c.MaxEntries))
+	}
+	if c.MaxAge > 0 {
+		opts = append(opts, WithMaxAge(c.MaxAge))
+	}
+	if c.DisableSingleflight {
+		opts = append(opts, WithSingleflight(false))
+	}
+	return opts
+}
@@ -0,0 +1,143 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package roundrobin
+
+import (
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+)
+
+// Option is the only struct that can be used to set config.
+type Option interface {
+	apply(cfg *config)
+}
+
+type option func(cfg *config)
+
+func (fn option) apply(cfg *config) {
+	fn(cfg)
+}
+
+type config struct {
+	name         string
+	maxIdle      time.Duration
+	maxEntries   int
+	maxAge       time.Duration
+	refreshFunc  func(cacheKey string) (discovery.Result, error)
+	cleanup      func(cacheKey string, instances []discovery.Instance)
+	singleflight bool
+	logger       hlog.FullLogger
+	sink         loadbalanceEx.Listener
+}
+
+func newConfig(opts []Option) *config {
+	cfg := &config{
+		name:         "round_robin",
+		singleflight: true,
+	}
+	for _, opt := range opts {
+		opt.apply(cfg)
+	}
+	return cfg
+}
+
+// WithName sets the name returned by Name, "round_robin" by default.
+// It tells balancers apart in metrics and debug pages.
+func WithName(name string) Option {
+	return option(func(cfg *config) {
+		cfg.name = name
+	})
+}
+
+// WithMaxIdle evicts the state of the cache keys which have not been picked for d, so that
+// clients talking to many short-lived services do not grow when Delete is never called.
+// Idle entries are evicted lazily by picks, at most once every d. It is disabled by default.
+func WithMaxIdle(d time.Duration) Option {
+	return option(func(cfg *config) {
+		cfg.maxIdle = d
+	})
+}
+
+// WithMaxEntries bounds the number of cache keys whose state is held to n, evicting the least recently
+// picked ones, so that clients talking to an unbounded set of services have a predictable memory use.
+// Past n cache keys, a miss evicts the least recently picked of a few sampled cache keys rather than
+// scanning them all, so the eviction is approximate. Evictions are counted by loadbalance.CacheStatsOf.
+// It is disabled by default.
+func WithMaxEntries(n int) Option {
+	return option(func(cfg *config) {
+		cfg.maxEntries = n
+	})
+}
+
+// WithMaxAge recomputes the state of a cache key from the instances of the picked result once it has not
+// been rebalanced for d, so that registries changing the instances or their weights without a rebalance are
+// eventually followed. The state and its position are kept when the instances did not change.
+// It is disabled by default. A result with more or fewer instances than the state is followed right away,
+// in the background, whatever the max age.
+func WithMaxAge(d time.Duration) Option {
+	return option(func(cfg *config) {
+		cfg.maxAge = d
+	})
+}
+
+// WithRefreshFunc makes the states older than the max age, see WithMaxAge, recomputed from the result
+// returned by fn instead of the picked result, which stays stale when the resolver stops pushing updates.
+// fn is called in the background with the cache key of the state, its errors are logged and the state
+// is kept until the next attempt, one max age later.
+func WithRefreshFunc(fn func(cacheKey string) (discovery.Result, error)) Option {
+	return option(func(cfg *config) {
+		cfg.refreshFunc = fn
+	})
+}
+
+// WithCleanup sets a function called once the state of a cache key is removed, by Delete or by an eviction,
+// with the instances it held, to tear down the resources kept per instance such as health probes or in-flight
+// counters. It is called once per removed state, synchronously, and must not block.
+func WithCleanup(fn func(cacheKey string, instances []discovery.Instance)) Option {
+	return option(func(cfg *config) {
+		cfg.cleanup = fn
+	})
+}
+
+// WithSingleflight sets whether concurrent picks missing the state of a cache key share one computation
+// with singleflight, enabled by default. Disabling it trades duplicate computations on the first picks
+// for no coordination between goroutines.
+func WithSingleflight(enabled bool) Option {
+	return option(func(cfg *config) {
+		cfg.singleflight = enabled
+	})
+}
+
+// WithLogger sets the logger of the verbose pick logs, see loadbalance.SetVerbose, and of the refresh errors.
+// The global hlog logger is used by default.
+func WithLogger(logger hlog.FullLogger) Option {
+	return option(func(cfg *config) {
+		cfg.logger = logger
+	})
+}
+
+// WithEventSink makes the balancer emit its picks, rebalances and deletes to sink,
+// like a loadbalance.Notifier wrapping it would. The methods of sink are called
+// synchronously and must not block.
+func WithEventSink(sink loadbalanceEx.Listener) Option {
+	return option(func(cfg *config) {
+		cfg.sink = sink
+	})
+}
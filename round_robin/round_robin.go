@@ -17,66 +17,580 @@
 package roundrobin
 
 import (
-	"sync"
+	"math/rand"
+	"sort"
+	"strconv"
 	"sync/atomic"
+	"time"
 
 	"github.com/cloudwego/hertz/pkg/app/client/discovery"
 	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
 	"golang.org/x/sync/singleflight"
 )
 
 type roundRobinBalancer struct {
-	cachedInfo sync.Map
-	sfg        singleflight.Group
+	// accessed atomically, first for 64-bit alignment
+	hits      uint64
+	misses    uint64
+	computes  uint64
+	evictions uint64
+	lastSweep int64
+	// starts with 64-bit atomic fields too
+	cachedInfo cache
+
+	cfg *config
+	sfg singleflight.Group
 }
 
 type roundRobinInfo struct {
-	instances []discovery.Instance
-	index     uint32
+	// accessed atomically, first for 64-bit alignment
+	lastPick int64 // unix nano
+	// number of picks, 64-bit so that the round is not broken by a wrap around
+	index uint64
+	// unix nano of the last time the instances were checked against the registry
+	refreshed int64
+
+	// set once a refresh is started for a result of a different length, accessed atomically
+	resizing uint32
+
+	fingerprint uint64
+	instances   []discovery.Instance
+	// number of instances of the result, before sanitizing
+	resultLen int
+	// position of the first pick in instances
+	start uint64
+}
+
+// continueFrom starts the round of r where the round of old stopped, at the instance old would
+// pick next if it is still there, so that rebalances do not send every client back to the first instance.
+func (r *roundRobinInfo) continueFrom(old *roundRobinInfo) {
+	if len(old.instances) == 0 || len(r.instances) == 0 {
+		return
+	}
+	pos := (old.start + atomic.LoadUint64(&old.index)) % uint64(len(old.instances))
+	next := old.instances[pos].Address()
+	for i, ins := range r.instances {
+		if addr := ins.Address(); addr.String() == next.String() && addr.Network() == next.Network() {
+			r.start = uint64(i)
+			return
+		}
+	}
+	// the next instance is gone, the position is kept
+	r.start = pos % uint64(len(r.instances))
+}
+
+// newRoundRobinInfo returns the state of instances, without their malformed and duplicate addresses.
+// The state holds its own copy of instances, resolvers may reuse the slice of their results.
+func newRoundRobinInfo(instances []discovery.Instance) *roundRobinInfo {
+	resultLen := len(instances)
+	if sanitized, _ := loadbalanceEx.SanitizeInstances(instances); len(sanitized) != len(instances) {
+		instances = sanitized
+	} else {
+		instances = append([]discovery.Instance(nil), instances...)
+	}
+	// registries may return the same instances in any order, sorting them keeps the pick sequence
+	sort.SliceStable(instances, func(i, j int) bool {
+		return instances[i].Address().String() < instances[j].Address().String()
+	})
+	now := time.Now().UnixNano()
+	return &roundRobinInfo{
+		lastPick:    now,
+		refreshed:   now,
+		fingerprint: fingerprint(instances),
+		instances:   instances,
+		resultLen:   resultLen,
+	}
+}
+
+// fingerprint hashes the addresses and weights of instances, whatever their order, so that it can be
+// computed from the instances of a result before sanitizing and sorting them. Each instance is hashed
+// with FNV-1a and mixed, and the hashes are summed. Instances without an address are skipped, like
+// SanitizeInstances does. Tags are not part of it since instances cannot enumerate them.
+func fingerprint(instances []discovery.Instance) uint64 {
+	var sum uint64
+	var buf [20]byte
+	for _, ins := range instances {
+		if ins == nil || ins.Address() == nil {
+			continue
+		}
+		addr := ins.Address()
+		h := fnv64a(14695981039346656037, addr.Network())
+		h = fnv64a(h, addr.String())
+		h = fnv64a(h, string(strconv.AppendInt(buf[:0], int64(ins.Weight()), 10)))
+		sum += mix64(h)
+	}
+	return sum
+}
+
+// fnv64a writes s to the FNV-1a hash h, followed by a separator.
+func fnv64a(h uint64, s string) uint64 {
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= 1099511628211
+	}
+	h ^= 0xff
+	return h * 1099511628211
+}
+
+// mix64 is the finalizer of SplitMix64, so that the sums of similar hashes do not collide.
+func mix64(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	return h ^ h>>31
+}
+
+// unchanged reports whether r is the state of instances, without building their state.
+func (r *roundRobinInfo) unchanged(instances []discovery.Instance) bool {
+	return len(instances) == r.resultLen && fingerprint(instances) == r.fingerprint
+}
+
+func init() {
+	loadbalanceEx.Register("round_robin", func() loadbalance.Loadbalancer {
+		return NewRoundRobinBalancer()
+	})
 }
 
 // NewRoundRobinBalancer creates a loadbalancer using round-robin algorithm.
-func NewRoundRobinBalancer() loadbalance.Loadbalancer {
-	lb := &roundRobinBalancer{}
+func NewRoundRobinBalancer(opts ...Option) loadbalance.Loadbalancer {
+	lb := &roundRobinBalancer{cfg: newConfig(opts)}
 	return lb
 }
 
 // Pick implements the Loadbalancer interface.
 func (rr *roundRobinBalancer) Pick(e discovery.Result) discovery.Instance {
-	ri, ok := rr.cachedInfo.Load(e.CacheKey)
-	if !ok {
-		ri, _, _ = rr.sfg.Do(e.CacheKey, func() (interface{}, error) {
-			return &roundRobinInfo{
-				instances: e.Instances,
-				index:     0,
-			}, nil
-		})
-		rr.cachedInfo.Store(e.CacheKey, ri)
+	r := rr.info(e)
+	if len(r.instances) == 0 {
+		if rr.cfg.sink != nil {
+			rr.cfg.sink.OnPick(loadbalanceEx.PickEvent{Balancer: rr.Name(), Result: e, Failure: loadbalanceEx.FailureReason(rr, e)})
+		}
+		return nil
 	}
 
-	r := ri.(*roundRobinInfo)
-	if len(r.instances) == 0 {
+	// the index still counts the picks of a single instance for the states
+	idx := r.start + atomic.AddUint64(&r.index, 1) - 1
+	ins := r.instances[0]
+	if len(r.instances) > 1 {
+		ins = r.instances[idx%uint64(len(r.instances))]
+	}
+	if loadbalanceEx.Verbose() {
+		rr.debugf("HERTZ: %s picked %s for %s at index %d of %d instances",
+			rr.Name(), ins.Address(), e.CacheKey, idx, len(r.instances))
+	}
+	if rr.cfg.sink != nil {
+		rr.cfg.sink.OnPick(loadbalanceEx.PickEvent{Balancer: rr.Name(), Result: e, Instance: ins})
+	}
+	return ins
+}
+
+// PickN implements the BatchPicker interface.
+// The n instances are the next n turns, taken with a single cache lookup and atomic increment.
+func (rr *roundRobinBalancer) PickN(e discovery.Result, n int) []discovery.Instance {
+	r := rr.info(e)
+	if len(r.instances) == 0 || n <= 0 {
+		if rr.cfg.sink != nil && n > 0 {
+			rr.cfg.sink.OnPick(loadbalanceEx.PickEvent{Balancer: rr.Name(), Result: e, Failure: loadbalanceEx.FailureReason(rr, e)})
+		}
 		return nil
 	}
 
-	newIdx := atomic.AddUint32(&r.index, 1)
-	return r.instances[(newIdx-1)%uint32(len(r.instances))]
+	start := r.start + atomic.AddUint64(&r.index, uint64(n)) - uint64(n)
+	picked := make([]discovery.Instance, n)
+	for i := range picked {
+		picked[i] = r.instances[(start+uint64(i))%uint64(len(r.instances))]
+		if rr.cfg.sink != nil {
+			rr.cfg.sink.OnPick(loadbalanceEx.PickEvent{Balancer: rr.Name(), Result: e, Instance: picked[i]})
+		}
+	}
+	if loadbalanceEx.Verbose() {
+		rr.debugf("HERTZ: %s picked %d instances for %s from index %d of %d instances",
+			rr.Name(), n, e.CacheKey, start, len(r.instances))
+	}
+	return picked
+}
+
+// info returns the cached state of e, computing it on a miss.
+func (rr *roundRobinBalancer) info(e discovery.Result) *roundRobinInfo {
+	r, ok := rr.cachedInfo.load(e.CacheKey)
+	if ok {
+		atomic.AddUint64(&rr.hits, 1)
+	} else {
+		atomic.AddUint64(&rr.misses, 1)
+		if rr.cfg.singleflight {
+			// only the computing goroutine stores, the waiters share its result
+			v, _, _ := rr.sfg.Do(e.CacheKey, func() (interface{}, error) {
+				return rr.compute(e), nil
+			})
+			r = v.(*roundRobinInfo)
+		} else {
+			// concurrent misses compute their own entry, the first stored one wins
+			r = rr.compute(e)
+		}
+	}
+
+	if len(e.Instances) != r.resultLen {
+		rr.resize(e, r)
+	} else if rr.cfg.maxAge > 0 {
+		r = rr.refresh(e, r)
+	}
+	if rr.cfg.maxIdle > 0 || rr.cfg.maxEntries > 0 {
+		rr.touch(r)
+	}
+	return r
+}
+
+// compute computes the state of e and stores it unless a rebalance stored a newer one meanwhile,
+// or the cache key was deleted meanwhile. It returns the cached state, or the computed one if deleted.
+func (rr *roundRobinBalancer) compute(e discovery.Result) *roundRobinInfo {
+	gen := rr.cachedInfo.generation(e.CacheKey)
+	atomic.AddUint64(&rr.computes, 1)
+	r, stored := rr.cachedInfo.loadOrStoreSince(e.CacheKey, newRoundRobinInfo(e.Instances), gen)
+	if stored {
+		rr.evictOverflow(e.CacheKey)
+	}
+	return r
+}
+
+// resize recomputes the state r of e in the background, once, when e has more or fewer instances
+// than the result r was computed from. The picks are served from r meanwhile.
+func (rr *roundRobinBalancer) resize(e discovery.Result, r *roundRobinInfo) {
+	if atomic.CompareAndSwapUint32(&r.resizing, 0, 1) {
+		atomic.StoreInt64(&r.refreshed, time.Now().UnixNano())
+		// the caller may reuse the instances once Pick returns
+		e.Instances = append([]discovery.Instance(nil), e.Instances...)
+		go rr.replace(e, r)
+	}
+}
+
+// refresh recomputes the state r of e from the instances of e once r is older than maxAge,
+// for registries changing the instances without a rebalance. Concurrent picks keep using r
+// while one of them refreshes it.
+func (rr *roundRobinBalancer) refresh(e discovery.Result, r *roundRobinInfo) *roundRobinInfo {
+	now := time.Now().UnixNano()
+	refreshed := atomic.LoadInt64(&r.refreshed)
+	if now-refreshed < int64(rr.cfg.maxAge) || !atomic.CompareAndSwapInt64(&r.refreshed, refreshed, now) {
+		return r
+	}
+	if rr.cfg.refreshFunc != nil {
+		go rr.refreshWithFunc(e.CacheKey, r)
+		return r
+	}
+	return rr.replace(e, r)
+}
+
+// refreshWithFunc replaces the state r of cacheKey by the state of the result of the refresh function.
+func (rr *roundRobinBalancer) refreshWithFunc(cacheKey string, r *roundRobinInfo) {
+	res, err := rr.cfg.refreshFunc(cacheKey)
+	if err != nil {
+		rr.warnf("HERTZ: %s failed to refresh the instances of %s: %v", rr.Name(), cacheKey, err)
+		return
+	}
+	res.CacheKey = cacheKey
+	rr.replace(res, r)
+}
+
+// replace replaces the state r of e by the state of the instances of e if they changed.
+func (rr *roundRobinBalancer) replace(e discovery.Result, r *roundRobinInfo) *roundRobinInfo {
+	if r.unchanged(e.Instances) {
+		return r
+	}
+	fresh := newRoundRobinInfo(e.Instances)
+	if fresh.fingerprint == r.fingerprint && len(fresh.instances) == len(r.instances) {
+		return r
+	}
+	atomic.AddUint64(&rr.computes, 1)
+	fresh.lastPick = atomic.LoadInt64(&r.lastPick)
+	fresh.continueFrom(r)
+	// a concurrent rebalance or delete wins over the refresh
+	if !rr.cachedInfo.compareAndSwap(e.CacheKey, r, fresh) {
+		return r
+	}
+	if rr.cfg.sink != nil {
+		rr.cfg.sink.OnRebalance(loadbalanceEx.RebalanceEvent{
+			Balancer: rr.Name(),
+			Result:   e,
+			Diff:     loadbalanceEx.NewDiff(e.CacheKey, r.instances, fresh.instances),
+		})
+	}
+	return fresh
+}
+
+func (rr *roundRobinBalancer) debugf(format string, v ...interface{}) {
+	if rr.cfg.logger != nil {
+		rr.cfg.logger.Debugf(format, v...)
+		return
+	}
+	hlog.Debugf(format, v...)
+}
+
+func (rr *roundRobinBalancer) warnf(format string, v ...interface{}) {
+	if rr.cfg.logger != nil {
+		rr.cfg.logger.Warnf(format, v...)
+		return
+	}
+	hlog.Warnf(format, v...)
+}
+
+// touch records a pick of r, and evicts the entries idle for longer than maxIdle
+// at most once every maxIdle.
+func (rr *roundRobinBalancer) touch(r *roundRobinInfo) {
+	now := time.Now().UnixNano()
+	atomic.StoreInt64(&r.lastPick, now)
+	maxIdle := int64(rr.cfg.maxIdle)
+	if maxIdle <= 0 {
+		return
+	}
+	lastSweep := atomic.LoadInt64(&rr.lastSweep)
+	if now-lastSweep < maxIdle || !atomic.CompareAndSwapInt64(&rr.lastSweep, lastSweep, now) {
+		return
+	}
+	evicted := rr.cachedInfo.deleteIf(func(r *roundRobinInfo) bool {
+		return now-atomic.LoadInt64(&r.lastPick) > maxIdle
+	})
+	atomic.AddUint64(&rr.evictions, uint64(len(evicted)))
+	for key, r := range evicted {
+		rr.cleanup(key, r)
+	}
+}
+
+// evictionSamples is the number of entries sampled to find the one to evict.
+const evictionSamples = 8
+
+// evictOverflow evicts entries other than the one of added while the cache holds more than maxEntries
+// entries. Like Redis, it approximates LRU by evicting the least recently picked of a few sampled entries,
+// so that a miss does not scan the whole cache.
+func (rr *roundRobinBalancer) evictOverflow(added string) {
+	if rr.cfg.maxEntries <= 0 {
+		return
+	}
+	for rr.cachedInfo.len() > rr.cfg.maxEntries {
+		var (
+			oldestKey string
+			oldest    *roundRobinInfo
+			oldestAt  int64
+			sampled   int
+		)
+		rr.cachedInfo.rangeFrom(rand.Intn(cacheShards), func(key string, r *roundRobinInfo) bool {
+			if key == added {
+				return true
+			}
+			if at := atomic.LoadInt64(&r.lastPick); oldest == nil || at < oldestAt {
+				oldestKey, oldest, oldestAt = key, r, at
+			}
+			sampled++
+			return sampled < evictionSamples
+		})
+		if oldest == nil {
+			return
+		}
+		// a concurrent rebalance of the oldest key replaced it, look for the oldest again
+		if rr.cachedInfo.compareAndDelete(oldestKey, oldest) {
+			atomic.AddUint64(&rr.evictions, 1)
+			rr.cleanup(oldestKey, oldest)
+		}
+	}
 }
 
 // Rebalance implements the Loadbalancer interface.
+// Registries often push identical results, the state of the cache key and its position in the
+// round are kept when the instances have the same addresses and weights as the cached ones, whatever
+// their order since instances are sorted by address. A change of the tags of the instances alone is not applied.
+// Identical results are detected from their fingerprint, before building their state.
 func (rr *roundRobinBalancer) Rebalance(e discovery.Result) {
-	rr.cachedInfo.Store(e.CacheKey, &roundRobinInfo{
-		instances: e.Instances,
-		index:     0,
-	})
+	diff := loadbalanceEx.Diff{CacheKey: e.CacheKey, Time: time.Now()}
+	old, ok := rr.cachedInfo.load(e.CacheKey)
+	if ok && old.unchanged(e.Instances) {
+		atomic.StoreInt64(&old.refreshed, diff.Time.UnixNano())
+	} else {
+		r := newRoundRobinInfo(e.Instances)
+		var oldInstances []discovery.Instance
+		if ok {
+			// rebalances do not count as picks for the idle eviction
+			r.lastPick = atomic.LoadInt64(&old.lastPick)
+			oldInstances = old.instances
+			r.continueFrom(old)
+		}
+		if !ok || old.fingerprint != r.fingerprint || len(old.instances) != len(r.instances) {
+			if rr.cachedInfo.store(e.CacheKey, r) {
+				rr.evictOverflow(e.CacheKey)
+			}
+		} else {
+			atomic.StoreInt64(&old.refreshed, r.refreshed)
+		}
+		if rr.cfg.sink != nil {
+			diff = loadbalanceEx.NewDiff(e.CacheKey, oldInstances, r.instances)
+		}
+	}
+	if rr.cfg.sink != nil {
+		event := loadbalanceEx.RebalanceEvent{
+			Balancer: rr.Name(),
+			Result:   e,
+			Diff:     diff,
+		}
+		_, event.Malformed = loadbalanceEx.SanitizeInstances(e.Instances)
+		rr.cfg.sink.OnRebalance(event)
+	}
+}
+
+// Prime implements the Primer interface.
+func (rr *roundRobinBalancer) Prime(e discovery.Result) {
+	if _, ok := rr.cachedInfo.load(e.CacheKey); ok {
+		return
+	}
+	atomic.AddUint64(&rr.computes, 1)
+	if _, stored := rr.cachedInfo.loadOrStore(e.CacheKey, newRoundRobinInfo(e.Instances)); stored {
+		rr.evictOverflow(e.CacheKey)
+	}
+}
+
+// cleanup calls the cleanup function, if any, with the instances of r, the removed state of cacheKey.
+func (rr *roundRobinBalancer) cleanup(cacheKey string, r *roundRobinInfo) {
+	if rr.cfg.cleanup != nil {
+		rr.cfg.cleanup(cacheKey, append([]discovery.Instance(nil), r.instances...))
+	}
 }
 
 // Delete implements the Loadbalancer interface.
+// Deleting a cache key without state, or deleting it twice, only emits the delete event.
 func (rr *roundRobinBalancer) Delete(cacheKey string) {
-	rr.cachedInfo.Delete(cacheKey)
+	if r, ok := rr.cachedInfo.delete(cacheKey); ok {
+		rr.cleanup(cacheKey, r)
+	}
+	if rr.cfg.sink != nil {
+		rr.cfg.sink.OnDelete(loadbalanceEx.DeleteEvent{Balancer: rr.Name(), CacheKey: cacheKey})
+	}
+}
+
+// Explain implements the Explainer interface.
+// The score of a candidate decreases with the number of picks before its turn,
+// instances of e missing from the cached instances are excluded.
+func (rr *roundRobinBalancer) Explain(e discovery.Result) loadbalanceEx.Decision {
+	d := loadbalanceEx.Decision{
+		Balancer: rr.Name(),
+		CacheKey: e.CacheKey,
+	}
+	// without a cached state, the first pick computes it from e
+	r, ok := rr.cachedInfo.load(e.CacheKey)
+	excluded := "not cached, waiting for a rebalance"
+	if !ok {
+		r, excluded = newRoundRobinInfo(e.Instances), "malformed address"
+	}
+	instances, index := r.instances, r.start+atomic.LoadUint64(&r.index)
+
+	n := uint64(len(instances))
+	cached := make(map[string]bool, n)
+	for i, ins := range instances {
+		addr := ins.Address().String()
+		cached[addr] = true
+		// picks before the turn of the instance
+		distance := (uint64(i) + n - index%n) % n
+		if distance == 0 {
+			d.Instance = ins
+		}
+		d.Candidates = append(d.Candidates, loadbalanceEx.Candidate{
+			Address: addr,
+			Weight:  ins.Weight(),
+			Score:   float64(n - distance),
+		})
+	}
+	for _, ins := range e.Instances {
+		if ins == nil || ins.Address() == nil {
+			continue
+		}
+		addr := ins.Address().String()
+		if !cached[addr] {
+			d.Candidates = append(d.Candidates, loadbalanceEx.Candidate{
+				Address:  addr,
+				Weight:   ins.Weight(),
+				Excluded: excluded,
+			})
+		}
+	}
+	return d
+}
+
+// CacheStats implements the CacheStatsProvider interface.
+func (rr *roundRobinBalancer) CacheStats() loadbalanceEx.CacheStats {
+	return loadbalanceEx.CacheStats{
+		Hits:      atomic.LoadUint64(&rr.hits),
+		Misses:    atomic.LoadUint64(&rr.misses),
+		Computes:  atomic.LoadUint64(&rr.computes),
+		Evictions: atomic.LoadUint64(&rr.evictions),
+		Entries:   rr.cachedInfo.len(),
+	}
+}
+
+// States implements the StateProvider interface.
+func (rr *roundRobinBalancer) States() []loadbalanceEx.State {
+	var states []loadbalanceEx.State
+	rr.cachedInfo.rangeAll(func(key string, r *roundRobinInfo) bool {
+		states = append(states, r.state(key))
+		return true
+	})
+	return states
+}
+
+// Snapshot implements the StateProvider interface.
+func (rr *roundRobinBalancer) Snapshot(cacheKey string) (loadbalanceEx.State, bool) {
+	r, ok := rr.cachedInfo.load(cacheKey)
+	if !ok {
+		return loadbalanceEx.State{}, false
+	}
+	return r.state(cacheKey), true
+}
+
+// Range implements the Ranger interface.
+func (rr *roundRobinBalancer) Range(cacheKey string, fn func(loadbalanceEx.InstanceState) bool) bool {
+	r, ok := rr.cachedInfo.load(cacheKey)
+	if !ok {
+		return false
+	}
+	picks := atomic.LoadUint64(&r.index)
+	for i := range r.instances {
+		if !fn(r.instanceState(i, picks)) {
+			break
+		}
+	}
+	return true
+}
+
+func (r *roundRobinInfo) state(cacheKey string) loadbalanceEx.State {
+	picks := atomic.LoadUint64(&r.index)
+	state := loadbalanceEx.State{
+		CacheKey:  cacheKey,
+		Instances: make([]loadbalanceEx.InstanceState, len(r.instances)),
+	}
+	for i := range r.instances {
+		state.Instances[i] = r.instanceState(i, picks)
+	}
+	return state
+}
+
+// instanceState returns the state of the i-th instance after picks picks.
+func (r *roundRobinInfo) instanceState(i int, picks uint64) loadbalanceEx.InstanceState {
+	ins := r.instances[i]
+	n := uint64(len(r.instances))
+	state := loadbalanceEx.InstanceState{
+		Address: ins.Address().String(),
+		Weight:  ins.Weight(),
+		Picks:   picks / n,
+	}
+	// the remaining picks went to the instances from start
+	if (uint64(i)+n-r.start%n)%n < picks%n {
+		state.Picks++
+	}
+	return state
+}
+
+// Weighted implements the WeightAware interface, round-robin ignores the weights of the instances.
+func (rr *roundRobinBalancer) Weighted() bool {
+	return false
 }
 
 // Name implements the Loadbalancer interface.
 func (rr *roundRobinBalancer) Name() string {
-	return "round_robin"
+	return rr.cfg.name
 }
@@ -17,14 +17,20 @@
 package roundrobin
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"net"
 	"strconv"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
 	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
 )
 
 func TestRoundRobinBalancer(t *testing.T) {
@@ -34,6 +40,7 @@ func TestRoundRobinBalancer(t *testing.T) {
 	assert.DeepEqual(t, ins, nil)
 	// test Name()
 	assert.DeepEqual(t, "round_robin", balancer.Name())
+	assert.DeepEqual(t, "rr", NewRoundRobinBalancer(WithName("rr")).Name())
 
 	// empty instance
 	e := discovery.Result{
@@ -122,3 +129,712 @@ func TestRaceProblem(t *testing.T) {
 	}()
 	wg.Wait()
 }
+
+func TestStates(t *testing.T) {
+	balancer := NewRoundRobinBalancer().(*roundRobinBalancer)
+	assert.DeepEqual(t, 0, len(balancer.States()))
+
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 20, nil),
+		},
+		CacheKey: "a",
+	}
+	balancer.Rebalance(e)
+	for i := 0; i < 3; i++ {
+		balancer.Pick(e)
+	}
+	states := balancer.States()
+	assert.DeepEqual(t, []loadbalanceEx.State{{
+		CacheKey: "a",
+		Instances: []loadbalanceEx.InstanceState{
+			{Address: "127.0.0.1:8880", Weight: 10, Picks: 2},
+			{Address: "127.0.0.1:8881", Weight: 20, Picks: 1},
+		},
+	}}, states)
+
+	state, ok := balancer.Snapshot("a")
+	assert.True(t, ok)
+	assert.DeepEqual(t, states[0], state)
+	// the snapshot is a copy
+	state.Instances[0].Weight = 0
+	state, _ = balancer.Snapshot("a")
+	assert.DeepEqual(t, 10, state.Instances[0].Weight)
+	_, ok = balancer.Snapshot("b")
+	assert.False(t, ok)
+
+	balancer.Delete("a")
+	assert.DeepEqual(t, 0, len(balancer.States()))
+}
+
+func TestExplain(t *testing.T) {
+	balancer := NewRoundRobinBalancer().(*roundRobinBalancer)
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8882", 10, nil),
+		},
+		CacheKey: "a",
+	}
+	// nothing cached yet, the first pick follows the sorted instances
+	unsorted := discovery.Result{
+		Instances: []discovery.Instance{e.Instances[2], e.Instances[0], discovery.NewInstance("tcp", "127.0.0.1:", 10, nil), e.Instances[1]},
+		CacheKey:  "c",
+	}
+	d := balancer.Explain(unsorted)
+	assert.DeepEqual(t, "127.0.0.1:8880", d.Instance.Address().String())
+	assert.DeepEqual(t, "malformed address", d.Candidates[3].Excluded)
+	assert.DeepEqual(t, d.Instance, balancer.Pick(unsorted))
+
+	balancer.Rebalance(e)
+	balancer.Pick(e)
+	d = balancer.Explain(e)
+	assert.DeepEqual(t, "round_robin", d.Balancer)
+	assert.DeepEqual(t, "a", d.CacheKey)
+	next := balancer.Pick(e)
+	assert.DeepEqual(t, next, d.Instance)
+	assert.DeepEqual(t, []loadbalanceEx.Candidate{
+		{Address: "127.0.0.1:8880", Weight: 10, Score: 1},
+		{Address: "127.0.0.1:8881", Weight: 10, Score: 3},
+		{Address: "127.0.0.1:8882", Weight: 10, Score: 2},
+	}, d.Candidates)
+
+	// instances of the result not cached yet
+	e.Instances = append(e.Instances, discovery.NewInstance("tcp", "127.0.0.1:8883", 10, nil))
+	d = balancer.Explain(e)
+	assert.DeepEqual(t, "127.0.0.1:8882", d.Instance.Address().String())
+	assert.DeepEqual(t, "127.0.0.1:8883", d.Candidates[3].Address)
+	assert.DeepEqual(t, float64(0), d.Candidates[3].Score)
+	assert.Assert(t, d.Candidates[3].Excluded != "")
+
+	// empty instance
+	d = balancer.Explain(discovery.Result{CacheKey: "b"})
+	assert.Nil(t, d.Instance)
+	assert.DeepEqual(t, 0, len(d.Candidates))
+}
+
+func TestCacheStats(t *testing.T) {
+	balancer := NewRoundRobinBalancer().(*roundRobinBalancer)
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+		},
+		CacheKey: "a",
+	}
+	balancer.Pick(e)
+	balancer.Pick(e)
+	balancer.Rebalance(discovery.Result{CacheKey: "b"})
+	balancer.Pick(discovery.Result{CacheKey: "b"})
+	assert.DeepEqual(t, loadbalanceEx.CacheStats{
+		Hits:     2,
+		Misses:   1,
+		Computes: 1,
+		Entries:  2,
+	}, balancer.CacheStats())
+
+	balancer.Delete("a")
+	assert.DeepEqual(t, 1, balancer.CacheStats().Entries)
+}
+
+func TestConfig(t *testing.T) {
+	cfg := Config{}
+	assert.Nil(t, cfg.Validate())
+	assert.DeepEqual(t, "round_robin", cfg.WithDefaults().Name)
+	assert.DeepEqual(t, "round_robin", NewRoundRobinBalancer(cfg.Options()...).Name())
+
+	cfg.Name = "rr"
+	assert.DeepEqual(t, "rr", NewRoundRobinBalancer(cfg.Options()...).Name())
+
+	cfg.MaxIdle = -time.Second
+	assert.NotNil(t, cfg.Validate())
+	cfg.MaxIdle = time.Minute
+	cfg.MaxEntries = -1
+	assert.NotNil(t, cfg.Validate())
+	cfg.MaxEntries = 100
+	cfg.MaxAge = -time.Second
+	assert.NotNil(t, cfg.Validate())
+	cfg.MaxAge = time.Second
+	cfg.DisableSingleflight = true
+	c := NewRoundRobinBalancer(cfg.Options()...).(*roundRobinBalancer).cfg
+	assert.DeepEqual(t, time.Minute, c.maxIdle)
+	assert.DeepEqual(t, 100, c.maxEntries)
+	assert.DeepEqual(t, time.Second, c.maxAge)
+	assert.False(t, c.singleflight)
+}
+
+func TestMaxIdle(t *testing.T) {
+	balancer := NewRoundRobinBalancer(WithMaxIdle(20 * time.Millisecond)).(*roundRobinBalancer)
+	a := discovery.Result{
+		Instances: []discovery.Instance{discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil)},
+		CacheKey:  "a",
+	}
+	b := discovery.Result{
+		Instances: []discovery.Instance{discovery.NewInstance("tcp", "127.0.0.1:8881", 10, nil)},
+		CacheKey:  "b",
+	}
+	balancer.Pick(a)
+	balancer.Pick(b)
+	time.Sleep(30 * time.Millisecond)
+	// rebalances do not keep an entry alive
+	balancer.Rebalance(b)
+	balancer.Pick(a)
+
+	_, ok := balancer.Snapshot("a")
+	assert.True(t, ok)
+	_, ok = balancer.Snapshot("b")
+	assert.False(t, ok)
+	assert.DeepEqual(t, uint64(1), balancer.CacheStats().Evictions)
+}
+
+func TestMaxEntries(t *testing.T) {
+	balancer := NewRoundRobinBalancer(WithMaxEntries(2)).(*roundRobinBalancer)
+	result := func(cacheKey string) discovery.Result {
+		return discovery.Result{
+			Instances: []discovery.Instance{discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil)},
+			CacheKey:  cacheKey,
+		}
+	}
+	balancer.Pick(result("a"))
+	time.Sleep(time.Millisecond)
+	balancer.Pick(result("b"))
+	time.Sleep(time.Millisecond)
+	balancer.Pick(result("a"))
+	time.Sleep(time.Millisecond)
+
+	// b is the least recently picked
+	balancer.Rebalance(result("c"))
+	_, ok := balancer.Snapshot("b")
+	assert.False(t, ok)
+	time.Sleep(time.Millisecond)
+	balancer.Pick(result("c"))
+	time.Sleep(time.Millisecond)
+	// the key of a pick is never evicted by its own miss
+	balancer.Pick(result("b"))
+	_, ok = balancer.Snapshot("a")
+	assert.False(t, ok)
+
+	stats := balancer.CacheStats()
+	assert.DeepEqual(t, 2, stats.Entries)
+	assert.DeepEqual(t, uint64(2), stats.Evictions)
+
+	// beyond a few entries, the evicted ones are sampled
+	balancer = NewRoundRobinBalancer(WithMaxEntries(100)).(*roundRobinBalancer)
+	for i := 0; i < 1000; i++ {
+		balancer.Pick(result(strconv.Itoa(i)))
+	}
+	stats = balancer.CacheStats()
+	assert.DeepEqual(t, 100, stats.Entries)
+	assert.DeepEqual(t, uint64(900), stats.Evictions)
+	_, ok = balancer.Snapshot("999")
+	assert.True(t, ok)
+}
+
+func TestMaxAge(t *testing.T) {
+	balancer := NewRoundRobinBalancer(WithMaxAge(20 * time.Millisecond)).(*roundRobinBalancer)
+	result := func(weight int) discovery.Result {
+		return discovery.Result{
+			Instances: []discovery.Instance{
+				discovery.NewInstance("tcp", "127.0.0.1:8880", weight, nil),
+				discovery.NewInstance("tcp", "127.0.0.1:8881", weight, nil),
+			},
+			CacheKey: "a",
+		}
+	}
+	balancer.Rebalance(result(10))
+	assert.DeepEqual(t, "127.0.0.1:8880", balancer.Pick(result(10)).Address().String())
+
+	// unchanged instances keep the position in the round
+	time.Sleep(30 * time.Millisecond)
+	assert.DeepEqual(t, "127.0.0.1:8881", balancer.Pick(result(10)).Address().String())
+
+	// changed weights are picked up once the state is older than the max age
+	balancer.Pick(result(20))
+	state, _ := balancer.Snapshot("a")
+	assert.DeepEqual(t, 10, state.Instances[0].Weight)
+	time.Sleep(30 * time.Millisecond)
+	assert.DeepEqual(t, "127.0.0.1:8881", balancer.Pick(result(20)).Address().String())
+	state, _ = balancer.Snapshot("a")
+	assert.DeepEqual(t, 20, state.Instances[0].Weight)
+	// rebalances are not computes, only the refresh is
+	assert.DeepEqual(t, uint64(1), balancer.CacheStats().Computes)
+}
+
+func TestResize(t *testing.T) {
+	balancer := NewRoundRobinBalancer().(*roundRobinBalancer)
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 10, nil),
+		},
+		CacheKey: "a",
+	}
+	balancer.Rebalance(e)
+
+	// the result grew without a rebalance, the pick is served from the cached instances
+	grown := discovery.Result{
+		Instances: append(e.Instances, discovery.NewInstance("tcp", "127.0.0.1:8882", 10, nil)),
+		CacheKey:  "a",
+	}
+	assert.DeepEqual(t, "127.0.0.1:8880", balancer.Pick(grown).Address().String())
+	for i := 0; i < 100; i++ {
+		if state, _ := balancer.Snapshot("a"); len(state.Instances) == 3 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	state, _ := balancer.Snapshot("a")
+	assert.DeepEqual(t, 3, len(state.Instances))
+	assert.DeepEqual(t, "127.0.0.1:8881", balancer.Pick(grown).Address().String())
+}
+
+func TestResizeReusedInstances(t *testing.T) {
+	balancer := NewRoundRobinBalancer().(*roundRobinBalancer)
+	instances := []discovery.Instance{
+		discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+		discovery.NewInstance("tcp", "127.0.0.1:8881", 10, nil),
+	}
+	balancer.Rebalance(discovery.Result{Instances: instances, CacheKey: "a"})
+
+	// the caller reuses its slice once the pick returns, while the resize runs in the background
+	buf := append(instances, discovery.NewInstance("tcp", "127.0.0.1:8882", 10, nil))
+	balancer.Pick(discovery.Result{Instances: buf, CacheKey: "a"})
+	buf[2] = discovery.NewInstance("tcp", "127.0.0.1:8883", 10, nil)
+	for i := 0; i < 100; i++ {
+		if state, _ := balancer.Snapshot("a"); len(state.Instances) == 3 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	state, _ := balancer.Snapshot("a")
+	assert.DeepEqual(t, 3, len(state.Instances))
+}
+
+func TestRefreshFunc(t *testing.T) {
+	calls := 0
+	logger := &warnLogger{warnings: make(chan string, 1)}
+	balancer := NewRoundRobinBalancer(WithMaxAge(20*time.Millisecond), WithLogger(logger),
+		WithRefreshFunc(func(cacheKey string) (discovery.Result, error) {
+			calls++
+			if calls == 1 {
+				return discovery.Result{}, errors.New("resolver unavailable")
+			}
+			return discovery.Result{
+				Instances: []discovery.Instance{discovery.NewInstance("tcp", "127.0.0.1:8881", 10, nil)},
+			}, nil
+		})).(*roundRobinBalancer)
+	stale := discovery.Result{
+		Instances: []discovery.Instance{discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil)},
+		CacheKey:  "a",
+	}
+	balancer.Rebalance(stale)
+
+	// the refresh fails, the state is kept
+	time.Sleep(30 * time.Millisecond)
+	assert.DeepEqual(t, "127.0.0.1:8880", balancer.Pick(stale).Address().String())
+	assert.DeepEqual(t, "HERTZ: round_robin failed to refresh the instances of a: resolver unavailable", <-logger.warnings)
+	// the next attempt, one max age later, replaces the state with the refreshed instances
+	time.Sleep(30 * time.Millisecond)
+	assert.DeepEqual(t, "127.0.0.1:8880", balancer.Pick(stale).Address().String())
+	for i := 0; i < 100; i++ {
+		if state, _ := balancer.Snapshot("a"); state.Instances[0].Address == "127.0.0.1:8881" {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	assert.DeepEqual(t, "127.0.0.1:8881", balancer.Pick(stale).Address().String())
+}
+
+func TestWithoutSingleflight(t *testing.T) {
+	balancer := NewRoundRobinBalancer(WithSingleflight(false)).(*roundRobinBalancer)
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 10, nil),
+		},
+		CacheKey: "a",
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			balancer.Pick(e)
+		}()
+	}
+	wg.Wait()
+	// concurrent misses converge on one entry which counts every pick
+	state, ok := balancer.Snapshot("a")
+	assert.True(t, ok)
+	assert.DeepEqual(t, uint64(5), state.Instances[0].Picks)
+	assert.DeepEqual(t, uint64(5), state.Instances[1].Picks)
+}
+
+type debugLogger struct {
+	hlog.FullLogger
+	logs []string
+}
+
+func (l *debugLogger) Debugf(format string, v ...interface{}) {
+	l.logs = append(l.logs, fmt.Sprintf(format, v...))
+}
+
+type warnLogger struct {
+	hlog.FullLogger
+	warnings chan string
+}
+
+func (l *warnLogger) Warnf(format string, v ...interface{}) {
+	l.warnings <- fmt.Sprintf(format, v...)
+}
+
+func TestLoggerAndEventSink(t *testing.T) {
+	logger := &debugLogger{}
+	var events []string
+	sink := loadbalanceEx.SynthesizedListener{
+		PickFunc: func(e loadbalanceEx.PickEvent) {
+			events = append(events, strings.TrimSpace("pick "+e.Balancer+" "+e.Failure))
+		},
+		RebalanceFunc: func(e loadbalanceEx.RebalanceEvent) {
+			events = append(events, fmt.Sprintf("rebalance %d added %d removed", len(e.Diff.Added), len(e.Diff.Removed)))
+		},
+		DeleteFunc: func(e loadbalanceEx.DeleteEvent) {
+			events = append(events, "delete "+e.CacheKey)
+		},
+	}
+	balancer := NewRoundRobinBalancer(WithName("rr"), WithLogger(logger), WithEventSink(sink))
+
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 10, nil),
+		},
+		CacheKey: "a",
+	}
+	loadbalanceEx.SetVerbose(true)
+	balancer.Pick(e)
+	loadbalanceEx.SetVerbose(false)
+	balancer.Pick(e)
+	balancer.Rebalance(discovery.Result{Instances: e.Instances[1:], CacheKey: "a"})
+	balancer.Delete("a")
+	balancer.Rebalance(discovery.Result{CacheKey: "a"})
+	balancer.Pick(discovery.Result{CacheKey: "a"})
+
+	assert.DeepEqual(t, []string{"HERTZ: rr picked 127.0.0.1:8880 for a at index 0 of 2 instances"}, logger.logs)
+	assert.DeepEqual(t, []string{
+		"pick rr", "pick rr", "rebalance 0 added 1 removed", "delete a",
+		"rebalance 0 added 0 removed", "pick rr no_instances",
+	}, events)
+}
+
+func TestCleanup(t *testing.T) {
+	var cleaned []string
+	balancer := NewRoundRobinBalancer(WithMaxEntries(1), WithCleanup(func(cacheKey string, instances []discovery.Instance) {
+		cleaned = append(cleaned, cacheKey+" "+instances[0].Address().String())
+	}))
+	result := func(cacheKey string) discovery.Result {
+		return discovery.Result{
+			Instances: []discovery.Instance{discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil)},
+			CacheKey:  cacheKey,
+		}
+	}
+	balancer.Rebalance(result("a"))
+	balancer.Delete("a")
+	// deleting again, or a cache key without state, does not clean up
+	balancer.Delete("a")
+	balancer.Delete("b")
+	assert.DeepEqual(t, []string{"a 127.0.0.1:8880"}, cleaned)
+
+	// evictions clean up too
+	balancer.Pick(result("b"))
+	balancer.Pick(result("c"))
+	assert.DeepEqual(t, []string{"a 127.0.0.1:8880", "b 127.0.0.1:8880"}, cleaned)
+}
+
+func TestRebalanceFingerprint(t *testing.T) {
+	balancer := NewRoundRobinBalancer()
+	result := func(weight int) discovery.Result {
+		return discovery.Result{
+			Instances: []discovery.Instance{
+				discovery.NewInstance("tcp", "127.0.0.1:8880", weight, nil),
+				discovery.NewInstance("tcp", "127.0.0.1:8881", weight, nil),
+			},
+			CacheKey: "a",
+		}
+	}
+	balancer.Rebalance(result(10))
+	assert.DeepEqual(t, "127.0.0.1:8880", balancer.Pick(result(10)).Address().String())
+
+	// an identical push keeps the position in the round
+	balancer.Rebalance(result(10))
+	assert.DeepEqual(t, "127.0.0.1:8881", balancer.Pick(result(10)).Address().String())
+	assert.DeepEqual(t, "127.0.0.1:8880", balancer.Pick(result(10)).Address().String())
+
+	// a weight change rebuilds the state, continuing the round
+	balancer.Rebalance(result(20))
+	assert.DeepEqual(t, "127.0.0.1:8881", balancer.Pick(result(20)).Address().String())
+	state, _ := loadbalanceEx.Snapshot(balancer, "a")
+	assert.DeepEqual(t, 20, state.Instances[0].Weight)
+
+	assert.False(t, fingerprint(result(10).Instances) == fingerprint(result(20).Instances))
+	assert.False(t, fingerprint(result(10).Instances[:1]) == fingerprint(result(10).Instances[1:]))
+	reversed := []discovery.Instance{result(10).Instances[1], result(10).Instances[0]}
+	assert.DeepEqual(t, fingerprint(result(10).Instances), fingerprint(reversed))
+}
+
+func TestStableOrder(t *testing.T) {
+	a := discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil)
+	b := discovery.NewInstance("tcp", "127.0.0.1:8881", 10, nil)
+	c := discovery.NewInstance("tcp", "127.0.0.1:8882", 10, nil)
+	picks := func(instances ...discovery.Instance) []string {
+		balancer := NewRoundRobinBalancer()
+		e := discovery.Result{Instances: instances, CacheKey: "a"}
+		var res []string
+		for i := 0; i < 3; i++ {
+			res = append(res, balancer.Pick(e).Address().String())
+		}
+		return res
+	}
+	expected := []string{"127.0.0.1:8880", "127.0.0.1:8881", "127.0.0.1:8882"}
+	assert.DeepEqual(t, expected, picks(a, b, c))
+	assert.DeepEqual(t, expected, picks(c, a, b))
+	assert.DeepEqual(t, expected, picks(b, c, a))
+
+	// a push reordering the same instances keeps the state
+	balancer := NewRoundRobinBalancer()
+	balancer.Rebalance(discovery.Result{Instances: []discovery.Instance{a, b, c}, CacheKey: "a"})
+	before, _ := loadbalanceEx.Snapshot(balancer, "a")
+	balancer.Rebalance(discovery.Result{Instances: []discovery.Instance{c, b, a}, CacheKey: "a"})
+	after, _ := loadbalanceEx.Snapshot(balancer, "a")
+	assert.DeepEqual(t, before.Instances, after.Instances)
+}
+
+func TestIndexWrapAround(t *testing.T) {
+	balancer := NewRoundRobinBalancer().(*roundRobinBalancer)
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8882", 10, nil),
+		},
+		CacheKey: "a",
+	}
+	balancer.Rebalance(e)
+	r, _ := balancer.cachedInfo.load("a")
+	// a 32-bit index would wrap around to 0 after the next pick and pick 8880 twice in a row
+	r.index = math.MaxUint32 - 1
+	for i := 0; i < 6; i++ {
+		expected := "127.0.0.1:888" + strconv.Itoa(int((math.MaxUint32-1+uint64(i))%3))
+		assert.DeepEqual(t, expected, balancer.Pick(e).Address().String())
+	}
+}
+
+func TestPickUsesCachedInstances(t *testing.T) {
+	balancer := NewRoundRobinBalancer()
+	cached := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8882", 10, nil),
+		},
+		CacheKey: "a",
+	}
+	balancer.Rebalance(cached)
+
+	// results not matching the cached instances, without a rebalance, pick from the cached ones
+	for _, e := range []discovery.Result{
+		{CacheKey: "a"},
+		{Instances: cached.Instances[:1], CacheKey: "a"},
+		{Instances: append(cached.Instances, discovery.NewInstance("tcp", "127.0.0.1:8883", 10, nil)), CacheKey: "a"},
+	} {
+		for i := 0; i < 3; i++ {
+			assert.DeepEqual(t, cached.Instances[i].Address(), balancer.Pick(e).Address())
+		}
+		for i, ins := range balancer.(*roundRobinBalancer).PickN(e, 3) {
+			assert.DeepEqual(t, cached.Instances[i].Address(), ins.Address())
+		}
+	}
+}
+
+func TestRebalanceContinuesRound(t *testing.T) {
+	balancer := NewRoundRobinBalancer().(*roundRobinBalancer)
+	result := func(ports ...int) discovery.Result {
+		e := discovery.Result{CacheKey: "a"}
+		for _, port := range ports {
+			e.Instances = append(e.Instances, discovery.NewInstance("tcp", "127.0.0.1:"+strconv.Itoa(port), 10, nil))
+		}
+		return e
+	}
+	balancer.Rebalance(result(8880, 8881, 8882))
+	balancer.Pick(result())
+	assert.DeepEqual(t, "127.0.0.1:8881", balancer.Pick(result()).Address().String())
+
+	// the next instance is kept, the round continues from it
+	balancer.Rebalance(result(8879, 8880, 8882, 8883))
+	assert.DeepEqual(t, "127.0.0.1:8882", balancer.Pick(result()).Address().String())
+	assert.DeepEqual(t, "127.0.0.1:8883", balancer.Pick(result()).Address().String())
+	assert.DeepEqual(t, "127.0.0.1:8879", balancer.Pick(result()).Address().String())
+	state, _ := balancer.Snapshot("a")
+	assert.DeepEqual(t, []uint64{1, 0, 1, 1}, []uint64{
+		state.Instances[0].Picks, state.Instances[1].Picks, state.Instances[2].Picks, state.Instances[3].Picks,
+	})
+	d := balancer.Explain(result(8879, 8880, 8882, 8883))
+	assert.DeepEqual(t, "127.0.0.1:8880", d.Instance.Address().String())
+
+	// the next instance is gone, the position is kept
+	balancer.Rebalance(result(8879, 8882, 8883))
+	assert.DeepEqual(t, "127.0.0.1:8882", balancer.Pick(result()).Address().String())
+}
+
+// blockingInstance blocks its first call to Address until unblock is closed.
+type blockingInstance struct {
+	discovery.Instance
+	once    sync.Once
+	called  chan struct{}
+	unblock chan struct{}
+}
+
+func (b *blockingInstance) Address() net.Addr {
+	b.once.Do(func() {
+		close(b.called)
+		<-b.unblock
+	})
+	return b.Instance.Address()
+}
+
+func TestDeleteDuringMiss(t *testing.T) {
+	balancer := NewRoundRobinBalancer().(*roundRobinBalancer)
+	ins := &blockingInstance{
+		Instance: discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+		called:   make(chan struct{}),
+		unblock:  make(chan struct{}),
+	}
+	e := discovery.Result{Instances: []discovery.Instance{ins}, CacheKey: "a"}
+
+	picked := make(chan discovery.Instance)
+	go func() {
+		picked <- balancer.Pick(e)
+	}()
+	// the pick is computing the state of a
+	<-ins.called
+	balancer.Delete("a")
+	close(ins.unblock)
+
+	// the pick is served but the delete is not undone
+	assert.NotNil(t, <-picked)
+	_, ok := balancer.Snapshot("a")
+	assert.False(t, ok)
+	balancer.Pick(e)
+	_, ok = balancer.Snapshot("a")
+	assert.True(t, ok)
+}
+
+func TestInstancesCopied(t *testing.T) {
+	balancer := NewRoundRobinBalancer()
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 10, nil),
+		},
+		CacheKey: "a",
+	}
+	balancer.Rebalance(e)
+	// the resolver reuses the slice of its result in place
+	e.Instances[0] = discovery.NewInstance("tcp", "127.0.0.1:8882", 10, nil)
+	assert.DeepEqual(t, "127.0.0.1:8880", balancer.Pick(e).Address().String())
+}
+
+func TestRebalanceDuringMiss(t *testing.T) {
+	for _, singleflight := range []bool{true, false} {
+		balancer := NewRoundRobinBalancer(WithSingleflight(singleflight)).(*roundRobinBalancer)
+		ins := &blockingInstance{
+			Instance: discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+			called:   make(chan struct{}),
+			unblock:  make(chan struct{}),
+		}
+		stale := discovery.Result{Instances: []discovery.Instance{ins}, CacheKey: "a"}
+		fresh := discovery.Result{
+			Instances: []discovery.Instance{discovery.NewInstance("tcp", "127.0.0.1:8881", 10, nil)},
+			CacheKey:  "a",
+		}
+
+		picked := make(chan discovery.Instance)
+		go func() {
+			picked <- balancer.Pick(stale)
+		}()
+		// the pick is computing the state of a from stale instances
+		<-ins.called
+		balancer.Rebalance(fresh)
+		close(ins.unblock)
+
+		// the newer rebalance wins, and serves the pick
+		assert.DeepEqual(t, "127.0.0.1:8881", (<-picked).Address().String())
+		state, _ := balancer.Snapshot("a")
+		assert.DeepEqual(t, "127.0.0.1:8881", state.Instances[0].Address)
+		assert.DeepEqual(t, uint64(1), balancer.CacheStats().Computes)
+	}
+}
+
+func TestPickAllocs(t *testing.T) {
+	balancer := NewRoundRobinBalancer(WithMaxIdle(time.Hour))
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 10, nil),
+		},
+		CacheKey: "a",
+	}
+	balancer.Rebalance(e)
+	// the picks of a cached key sit on the request path and must not allocate
+	allocs := testing.AllocsPerRun(1000, func() {
+		balancer.Pick(e)
+	})
+	assert.DeepEqual(t, float64(0), allocs)
+}
+
+func newBenchmarkResult() discovery.Result {
+	e := discovery.Result{CacheKey: "a"}
+	for i := 0; i < 10; i++ {
+		e.Instances = append(e.Instances, discovery.NewInstance("tcp", "127.0.0.1:"+strconv.Itoa(8880+i), 10, nil))
+	}
+	return e
+}
+
+func BenchmarkPick(b *testing.B) {
+	balancer := NewRoundRobinBalancer()
+	e := newBenchmarkResult()
+	balancer.Rebalance(e)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		balancer.Pick(e)
+	}
+}
+
+func BenchmarkPickParallel(b *testing.B) {
+	balancer := NewRoundRobinBalancer()
+	e := newBenchmarkResult()
+	balancer.Rebalance(e)
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			balancer.Pick(e)
+		}
+	})
+}
+
+func BenchmarkPickSingleInstance(b *testing.B) {
+	balancer := NewRoundRobinBalancer()
+	e := discovery.Result{
+		Instances: []discovery.Instance{discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil)},
+		CacheKey:  "a",
+	}
+	balancer.Rebalance(e)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		balancer.Pick(e)
+	}
+}
@@ -0,0 +1,163 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance
+
+import (
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+)
+
+// Router is a Loadbalancer delegating each cache key, that is each service, to the balancer routed for it,
+// so that one client can balance its services with different algorithms.
+type Router struct {
+	route    func(cacheKey string) loadbalance.Loadbalancer
+	fallback loadbalance.Loadbalancer
+	// balancers are the known balancers, for States
+	balancers []loadbalance.Loadbalancer
+}
+
+// NewRouter creates a Router delegating the cache keys of routes to their balancer, and the other ones to fallback.
+// Cache keys are prefixed with the name of the resolver, e.g. "nacos:hertz.test.demo".
+func NewRouter(routes map[string]loadbalance.Loadbalancer, fallback loadbalance.Loadbalancer) *Router {
+	m := make(map[string]loadbalance.Loadbalancer, len(routes))
+	for key, lb := range routes {
+		m[key] = lb
+	}
+	r := NewRouterFunc(func(cacheKey string) loadbalance.Loadbalancer {
+		return m[cacheKey]
+	}, fallback)
+	for _, lb := range m {
+		r.addBalancer(lb)
+	}
+	return r
+}
+
+// NewRouterFunc creates a Router delegating each cache key to the balancer returned by route,
+// or to fallback if it returns nil. route must always return the same balancer for a cache key.
+// fallback may be nil, in which case the picks of the cache keys without a route return nil.
+// Since the routes cannot be listed, States only returns the states of fallback.
+func NewRouterFunc(route func(cacheKey string) loadbalance.Loadbalancer, fallback loadbalance.Loadbalancer) *Router {
+	r := &Router{route: route, fallback: fallback}
+	r.addBalancer(fallback)
+	return r
+}
+
+func (r *Router) addBalancer(lb loadbalance.Loadbalancer) {
+	if lb == nil {
+		return
+	}
+	for _, known := range r.balancers {
+		if known == lb {
+			return
+		}
+	}
+	r.balancers = append(r.balancers, lb)
+}
+
+func (r *Router) balancer(cacheKey string) loadbalance.Loadbalancer {
+	if lb := r.route(cacheKey); lb != nil {
+		return lb
+	}
+	return r.fallback
+}
+
+// Pick implements the Loadbalancer interface.
+func (r *Router) Pick(e discovery.Result) discovery.Instance {
+	lb := r.balancer(e.CacheKey)
+	if lb == nil {
+		return nil
+	}
+	return lb.Pick(e)
+}
+
+// Rebalance implements the Loadbalancer interface.
+func (r *Router) Rebalance(e discovery.Result) {
+	if lb := r.balancer(e.CacheKey); lb != nil {
+		lb.Rebalance(e)
+	}
+}
+
+// Prime implements the Primer interface, it primes the balancer of the cache key of e.
+func (r *Router) Prime(e discovery.Result) {
+	if lb := r.balancer(e.CacheKey); lb != nil {
+		Prime(lb, e)
+	}
+}
+
+// Delete implements the Loadbalancer interface.
+func (r *Router) Delete(cacheKey string) {
+	if lb := r.balancer(cacheKey); lb != nil {
+		lb.Delete(cacheKey)
+	}
+}
+
+// Explain implements the Explainer interface, with the balancer of the cache key of e.
+// The instances are excluded when no balancer is routed for the cache key.
+func (r *Router) Explain(e discovery.Result) Decision {
+	lb := r.balancer(e.CacheKey)
+	if lb == nil {
+		d := Decision{Balancer: r.Name(), CacheKey: e.CacheKey}
+		for _, ins := range e.Instances {
+			if addr, ok := validAddress(ins); ok {
+				d.Candidates = append(d.Candidates, Candidate{
+					Address:  addr,
+					Weight:   ins.Weight(),
+					Excluded: "no balancer routed",
+				})
+			}
+		}
+		return d
+	}
+	return explainAll(lb, e)
+}
+
+// States implements the StateProvider interface, it returns the states of the known balancers
+// for the cache keys routed to them.
+func (r *Router) States() []State {
+	var states []State
+	for _, lb := range r.balancers {
+		for _, state := range States(lb) {
+			if r.balancer(state.CacheKey) == lb {
+				states = append(states, state)
+			}
+		}
+	}
+	return states
+}
+
+// Snapshot implements the StateProvider interface, with the balancer of the cache key.
+func (r *Router) Snapshot(cacheKey string) (State, bool) {
+	lb := r.balancer(cacheKey)
+	if lb == nil {
+		return State{}, false
+	}
+	return Snapshot(lb, cacheKey)
+}
+
+// Range implements the Ranger interface, with the balancer of the cache key.
+func (r *Router) Range(cacheKey string, fn func(InstanceState) bool) bool {
+	lb := r.balancer(cacheKey)
+	if lb == nil {
+		return false
+	}
+	return Range(lb, cacheKey, fn)
+}
+
+// Name implements the Loadbalancer interface.
+func (r *Router) Name() string {
+	return "router"
+}
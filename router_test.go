@@ -0,0 +1,92 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance_test
+
+import (
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+	roundrobin "github.com/hertz-contrib/loadbalance/round_robin"
+)
+
+func TestRouter(t *testing.T) {
+	rr := roundrobin.NewRoundRobinBalancer()
+	r := loadbalanceEx.NewRouter(map[string]loadbalance.Loadbalancer{
+		"nacos:api": rr,
+	}, firstBalancer{})
+	assert.DeepEqual(t, "router", r.Name())
+
+	instances := []discovery.Instance{
+		discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+		discovery.NewInstance("tcp", "127.0.0.1:8881", 10, nil),
+	}
+	api := discovery.Result{Instances: instances, CacheKey: "nacos:api"}
+	cache := discovery.Result{Instances: instances, CacheKey: "nacos:cache"}
+	r.Rebalance(api)
+	r.Rebalance(cache)
+	for i := 0; i < 2; i++ {
+		assert.DeepEqual(t, instances[i], r.Pick(api))
+		assert.DeepEqual(t, instances[0], r.Pick(cache))
+	}
+	_, ok := loadbalanceEx.Snapshot(rr, "nacos:api")
+	assert.True(t, ok)
+	_, ok = loadbalanceEx.Snapshot(rr, "nacos:cache")
+	assert.False(t, ok)
+
+	// the state and the decision are the ones of the routed balancer
+	state, ok := loadbalanceEx.Snapshot(r, "nacos:api")
+	assert.True(t, ok)
+	assert.DeepEqual(t, 2, len(state.Instances))
+	states := loadbalanceEx.States(r)
+	assert.DeepEqual(t, 1, len(states))
+	assert.DeepEqual(t, "nacos:api", states[0].CacheKey)
+	d, ok := loadbalanceEx.Explain(r, api)
+	assert.True(t, ok)
+	assert.DeepEqual(t, rr.Name(), d.Balancer)
+	assert.DeepEqual(t, instances[0], d.Instance)
+
+	r.Delete("nacos:api")
+	_, ok = loadbalanceEx.Snapshot(rr, "nacos:api")
+	assert.False(t, ok)
+}
+
+func TestRouterWithoutFallback(t *testing.T) {
+	rr := roundrobin.NewRoundRobinBalancer()
+	r := loadbalanceEx.NewRouterFunc(func(cacheKey string) loadbalance.Loadbalancer {
+		return nil
+	}, nil)
+	e := discovery.Result{
+		Instances: []discovery.Instance{discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil)},
+		CacheKey:  "nacos:api",
+	}
+	r.Rebalance(e)
+	loadbalanceEx.Prime(r, e)
+	assert.Nil(t, r.Pick(e))
+	r.Delete("nacos:api")
+
+	_, err := loadbalanceEx.TryPick(r, e)
+	assert.DeepEqual(t, loadbalanceEx.ErrAllExcluded, err)
+
+	// the state of the fallback is reachable through the router
+	r = loadbalanceEx.NewRouter(nil, rr)
+	r.Rebalance(e)
+	_, ok := loadbalanceEx.Snapshot(r, "nacos:api")
+	assert.True(t, ok)
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package loadbalance
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+	"github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/protocol"
+)
+
+const rulesDefault = "default"
+
+// RequestRule routes the requests carrying headers, or a path prefix, to a group of instances.
+type RequestRule struct {
+	// Name names the group, the wrapped balancer holds its state under the cache key suffixed with "#" and Name.
+	// The instances belonging to no group are under "default".
+	Name string
+	// Headers are the values of the headers a request must carry to match, all of them.
+	Headers map[string]string
+	// PathPrefix is the prefix the path of a request must have to match.
+	PathPrefix string
+	// Instances selects the instances of the group.
+	Instances *TagExpr
+}
+
+func (r *RequestRule) match(req *protocol.Request) bool {
+	for k, v := range r.Headers {
+		if string(req.Header.Peek(k)) != v {
+			return false
+		}
+	}
+	return bytes.HasPrefix(req.URI().Path(), []byte(r.PathPrefix))
+}
+
+// RequestRouter is a Loadbalancer routing requests to groups of instances by their headers and path before
+// balancing, e.g. the requests carrying `X-Debug: 1` to the instances tagged debug. Balancers do not see the
+// requests: Route applies the rules from a client middleware used before sd.Discovery, the other requests are
+// balanced by the wrapped balancer among the instances belonging to no group.
+// Like Split, all the instances are picked when they all belong to a group.
+type RequestRouter struct {
+	splitter
+	rules []RequestRule
+}
+
+// NewRequestRouter creates a RequestRouter applying rules in order, an instance belonging to the group of
+// the first rule it matches, and balancing the picks of each group with lb. It panics if a rule has no Instances.
+func NewRequestRouter(lb loadbalance.Loadbalancer, rules []RequestRule) *RequestRouter {
+	rules = append([]RequestRule(nil), rules...)
+	for _, r := range rules {
+		if r.Instances == nil {
+			panic(fmt.Sprintf("loadbalance: NewRequestRouter rule %q has no Instances", r.Name))
+		}
+	}
+	rr := &RequestRouter{splitter: splitter{lb: lb, excluded: "reserved for the requests matching its rule", key: func(ins discovery.Instance) (string, bool) {
+		for _, r := range rules {
+			if r.Instances.Match(ins) {
+				return r.Name, true
+			}
+		}
+		return rulesDefault, true
+	}}, rules: rules}
+	rr.setWeights(map[string]int{rulesDefault: 1})
+	return rr
+}
+
+// Route sends req to an instance of the cache key picked in the group of the first rule matching req, and
+// returns the name of the rule. The request then bypasses service discovery, like with WithForcedAddress.
+// It returns false if no rule matches, if the group has no instances or if the cache key was neither
+// rebalanced nor picked yet, leaving req to sd.Discovery.
+func (rr *RequestRouter) Route(cacheKey string, req *protocol.Request) (string, bool) {
+	if !req.Options().IsSD() {
+		return "", false
+	}
+	groups, ok := rr.groups.Load(cacheKey)
+	if !ok {
+		return "", false
+	}
+	for i := range rr.rules {
+		r := &rr.rules[i]
+		if !r.match(req) {
+			continue
+		}
+		g := groups.(map[string]discovery.Result)[r.Name]
+		if len(g.Instances) == 0 {
+			return "", false
+		}
+		ins := rr.lb.Pick(g)
+		if ins == nil {
+			return "", false
+		}
+		req.SetOptions(config.WithSD(false))
+		req.SetHost(ins.Address().String())
+		return r.Name, true
+	}
+	return "", false
+}
+
+// Explain implements the Explainer interface.
+func (rr *RequestRouter) Explain(e discovery.Result) Decision {
+	return rr.explain(rr.Name(), e)
+}
+
+// Name implements the Loadbalancer interface.
+func (rr *RequestRouter) Name() string {
+	return "request_router"
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package loadbalance_test
+
+import (
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	"github.com/cloudwego/hertz/pkg/protocol"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+	roundrobin "github.com/hertz-contrib/loadbalance/round_robin"
+)
+
+func TestRequestRouter(t *testing.T) {
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 10, map[string]string{"debug": "true"}),
+			discovery.NewInstance("tcp", "127.0.0.1:8882", 10, map[string]string{"admin": "true"}),
+		},
+		CacheKey: "a",
+	}
+	rr := loadbalanceEx.NewRequestRouter(roundrobin.NewRoundRobinBalancer(), []loadbalanceEx.RequestRule{
+		{Name: "debug", Headers: map[string]string{"X-Debug": "1"}, Instances: loadbalanceEx.MustParseTagExpr("debug")},
+		{Name: "admin", PathPrefix: "/admin/", Instances: loadbalanceEx.MustParseTagExpr("admin")},
+	})
+	request := func(path string, headers ...string) *protocol.Request {
+		req := &protocol.Request{}
+		req.SetOptions(config.WithSD(true))
+		req.SetRequestURI("http://hertz.test.demo" + path)
+		for i := 0; i < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		return req
+	}
+	// not rebalanced yet
+	_, ok := rr.Route("a", request("/", "X-Debug", "1"))
+	assert.False(t, ok)
+
+	rr.Rebalance(e)
+	req := request("/ping", "X-Debug", "1")
+	name, ok := rr.Route("a", req)
+	assert.True(t, ok)
+	assert.DeepEqual(t, "debug", name)
+	assert.DeepEqual(t, "127.0.0.1:8881", string(req.Host()))
+	assert.False(t, req.Options().IsSD())
+
+	req = request("/admin/users")
+	name, _ = rr.Route("a", req)
+	assert.DeepEqual(t, "admin", name)
+	assert.DeepEqual(t, "127.0.0.1:8882", string(req.Host()))
+
+	// other requests are balanced among the instances belonging to no group
+	req = request("/ping", "X-Debug", "0")
+	_, ok = rr.Route("a", req)
+	assert.False(t, ok)
+	assert.True(t, req.Options().IsSD())
+	for i := 0; i < 10; i++ {
+		assert.DeepEqual(t, "127.0.0.1:8880", rr.Pick(e).Address().String())
+	}
+	assert.DeepEqual(t, "request_router", rr.Name())
+}
+
+func TestRequestRouterWithoutInstances(t *testing.T) {
+	defer func() {
+		assert.True(t, recover() != nil)
+	}()
+	loadbalanceEx.NewRequestRouter(roundrobin.NewRoundRobinBalancer(), []loadbalanceEx.RequestRule{
+		{Name: "debug", Headers: map[string]string{"X-Debug": "1"}},
+	})
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance
+
+import (
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+)
+
+// SanitizeInstances drops the instances with a malformed address and merges the instances sharing
+// an address, which registries often return during rolling deploys, into the first of them with the
+// largest of their weights. It returns the malformed addresses, and instances itself if it is clean.
+func SanitizeInstances(instances []discovery.Instance) (sanitized []discovery.Instance, malformed []string) {
+	clean := true
+	seen := make(map[string]int, len(instances))
+	for _, ins := range instances {
+		addr, ok := validAddress(ins)
+		if !ok {
+			malformed = append(malformed, addr)
+			clean = false
+			continue
+		}
+		if _, ok := seen[addr]; ok {
+			clean = false
+			continue
+		}
+		seen[addr] = 0
+	}
+	if clean {
+		return instances, nil
+	}
+
+	sanitized = make([]discovery.Instance, 0, len(seen))
+	for _, ins := range instances {
+		addr, ok := validAddress(ins)
+		if !ok {
+			continue
+		}
+		i, ok := seen[addr]
+		if ok && i > 0 {
+			if ins.Weight() > sanitized[i-1].Weight() {
+				sanitized[i-1] = ins
+			}
+			continue
+		}
+		sanitized = append(sanitized, ins)
+		// indexes are offset by one, 0 marks an address not appended yet
+		seen[addr] = len(sanitized)
+	}
+	return sanitized, malformed
+}
+
+// validAddress returns the address of ins, and whether it can be dialed: a path for unix sockets, a host
+// with an optional port for IP networks. Hosts without a port are dialed on the default port of the scheme.
+func validAddress(ins discovery.Instance) (string, bool) {
+	if ins == nil || ins.Address() == nil {
+		return "", false
+	}
+	addr := ins.Address()
+	s := addr.String()
+	if s == "" || strings.HasPrefix(addr.Network(), "unix") {
+		return s, s != ""
+	}
+	_, port, err := net.SplitHostPort(s)
+	if err == nil {
+		return s, port != ""
+	}
+	if net.ParseIP(s) != nil {
+		return s, true
+	}
+	var addrErr *net.AddrError
+	return s, errors.As(err, &addrErr) && addrErr.Err == "missing port in address"
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+	roundrobin "github.com/hertz-contrib/loadbalance/round_robin"
+)
+
+func TestSanitizeInstances(t *testing.T) {
+	clean := []discovery.Instance{
+		discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+		discovery.NewInstance("unix", "/tmp/hertz.sock", 10, nil),
+	}
+	sanitized, malformed := loadbalanceEx.SanitizeInstances(clean)
+	assert.True(t, &clean[0] == &sanitized[0])
+	assert.Nil(t, malformed)
+
+	sanitized, malformed = loadbalanceEx.SanitizeInstances([]discovery.Instance{
+		discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+		discovery.NewInstance("tcp", "127.0.0.1:", 10, nil),
+		discovery.NewInstance("tcp", "127.0.0.1:8881", 10, nil),
+		discovery.NewInstance("tcp", "127.0.0.1:8880", 20, nil),
+		discovery.NewInstance("tcp", "127.0.0.1:8880", 5, nil),
+	})
+	assert.DeepEqual(t, []string{"127.0.0.1:"}, malformed)
+	assert.DeepEqual(t, 2, len(sanitized))
+	// the first position of an address is kept, with the largest weight
+	assert.DeepEqual(t, "127.0.0.1:8880", sanitized[0].Address().String())
+	assert.DeepEqual(t, 20, sanitized[0].Weight())
+	assert.DeepEqual(t, "127.0.0.1:8881", sanitized[1].Address().String())
+
+	// hosts without a port are dialed on the default port
+	hostOnly := []discovery.Instance{
+		discovery.NewInstance("tcp", "10.0.0.1", 10, nil),
+		discovery.NewInstance("tcp", "svc.internal", 10, nil),
+		discovery.NewInstance("tcp", "::1", 10, nil),
+		discovery.NewInstance("tcp", "[::1]:8080", 10, nil),
+	}
+	sanitized, malformed = loadbalanceEx.SanitizeInstances(hostOnly)
+	assert.DeepEqual(t, 4, len(sanitized))
+	assert.Nil(t, malformed)
+	_, malformed = loadbalanceEx.SanitizeInstances([]discovery.Instance{
+		discovery.NewInstance("tcp", "[::1", 10, nil),
+		discovery.NewInstance("tcp", "a:b:c]", 10, nil),
+	})
+	assert.DeepEqual(t, []string{"[::1", "a:b:c]"}, malformed)
+	lb := roundrobin.NewRoundRobinBalancer()
+	ins := lb.Pick(discovery.Result{Instances: hostOnly[:1], CacheKey: "a"})
+	assert.DeepEqual(t, "10.0.0.1", ins.Address().String())
+}
+
+func TestSanitizedRebalance(t *testing.T) {
+	n := loadbalanceEx.NewNotifier(roundrobin.NewRoundRobinBalancer())
+	var malformed []string
+	n.AddListener(loadbalanceEx.SynthesizedListener{
+		RebalanceFunc: func(e loadbalanceEx.RebalanceEvent) { malformed = e.Malformed },
+	})
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+			discovery.NewInstance("tcp", "localhost:", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 10, nil),
+		},
+		CacheKey: "a",
+	}
+	n.Rebalance(e)
+	assert.DeepEqual(t, []string{"localhost:"}, malformed)
+	// duplicates are not picked twice per round
+	for i := 0; i < 4; i++ {
+		assert.DeepEqual(t, "127.0.0.1:888"+strconv.Itoa(i%2), n.Pick(e).Address().String())
+	}
+}
@@ -0,0 +1,181 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance
+
+import (
+	"sync"
+	"time"
+)
+
+// SkewDetectorOption configures a SkewDetector.
+type SkewDetectorOption func(d *SkewDetector)
+
+// WithCheckInterval sets how often the SkewDetector checks the picks, 10 seconds by default
+// or if interval is not positive.
+func WithCheckInterval(interval time.Duration) SkewDetectorOption {
+	return func(d *SkewDetector) {
+		d.interval = interval
+	}
+}
+
+// WithMinPicks sets the number of picks of a cache key below which a check is skipped,
+// since few picks deviate from the weights by chance. It is 100 by default.
+func WithMinPicks(picks uint64) SkewDetectorOption {
+	return func(d *SkewDetector) {
+		d.minPicks = picks
+	}
+}
+
+// defaultCheckInterval is the check interval of a SkewDetector created without a positive one.
+const defaultCheckInterval = 10 * time.Second
+
+// checkpoint is the report of a cache key at a check.
+type checkpoint struct {
+	report FairnessReport
+	at     time.Time
+}
+
+// SkewDetector periodically compares the picks counted by a FairnessTracker with the expected ones,
+// following the Expectation of the tracker, and calls a callback when the MaxDeviation of a cache key stays above
+// a threshold for a sustained period, which catches silent balancing bugs.
+// Each check only considers the picks since the previous check, so the FairnessTracker
+// can still be reset by others.
+type SkewDetector struct {
+	ft        *FairnessTracker
+	threshold float64
+	sustain   time.Duration
+	onSkew    func(r FairnessReport)
+	interval  time.Duration
+	minPicks  uint64
+
+	// only accessed by the loop goroutine
+	last        map[string]checkpoint
+	skewedSince map[string]time.Time
+	alerted     map[string]bool
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// NewSkewDetector creates a SkewDetector checking the reports of ft in the background until Close is called.
+// onSkew is called once a cache key has been skewed beyond threshold for sustain, and not again until
+// the skew disappears. It receives the report of the picks since the previous check.
+func NewSkewDetector(ft *FairnessTracker, threshold float64, sustain time.Duration, onSkew func(r FairnessReport), opts ...SkewDetectorOption) *SkewDetector {
+	d := &SkewDetector{
+		ft:          ft,
+		threshold:   threshold,
+		sustain:     sustain,
+		onSkew:      onSkew,
+		interval:    defaultCheckInterval,
+		minPicks:    100,
+		last:        make(map[string]checkpoint),
+		skewedSince: make(map[string]time.Time),
+		alerted:     make(map[string]bool),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	if d.interval <= 0 {
+		d.interval = defaultCheckInterval
+	}
+	go d.loop()
+	return d
+}
+
+// Close stops the SkewDetector.
+func (d *SkewDetector) Close() {
+	d.once.Do(func() {
+		close(d.stop)
+	})
+	<-d.done
+}
+
+func (d *SkewDetector) loop() {
+	defer close(d.done)
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.stop:
+			return
+		case now := <-ticker.C:
+			d.check(now)
+		}
+	}
+}
+
+func (d *SkewDetector) check(now time.Time) {
+	reports := d.ft.Reports()
+	seen := make(map[string]bool, len(reports))
+	for _, r := range reports {
+		seen[r.CacheKey] = true
+		last, ok := d.last[r.CacheKey]
+		d.last[r.CacheKey] = checkpoint{report: r, at: now}
+		// the window of the tracker was not reset since the previous check
+		if ok && last.report.Since.Equal(r.Since) {
+			r = since(d.ft.expect, last, r)
+		}
+		if r.Picks < d.minPicks {
+			continue
+		}
+		if r.MaxDeviation <= d.threshold {
+			delete(d.skewedSince, r.CacheKey)
+			delete(d.alerted, r.CacheKey)
+			continue
+		}
+		start, ok := d.skewedSince[r.CacheKey]
+		if !ok {
+			start = r.Since
+			d.skewedSince[r.CacheKey] = start
+		}
+		if !d.alerted[r.CacheKey] && now.Sub(start) >= d.sustain {
+			d.alerted[r.CacheKey] = true
+			d.onSkew(r)
+		}
+	}
+	for key := range d.last {
+		if !seen[key] {
+			delete(d.last, key)
+			delete(d.skewedSince, key)
+			delete(d.alerted, key)
+		}
+	}
+}
+
+// since returns the report of the picks of cur which happened after the last check.
+func since(expect Expectation, last checkpoint, cur FairnessReport) FairnessReport {
+	lastPicks := make(map[string]uint64, len(last.report.Instances))
+	for _, f := range last.report.Instances {
+		lastPicks[f.Address] = f.Picks
+	}
+	w := &fairnessWindow{
+		expect:  expect,
+		since:   last.at,
+		weights: make(map[string]int, len(cur.Instances)),
+		picks:   make(map[string]uint64, len(cur.Instances)),
+	}
+	for _, f := range cur.Instances {
+		w.weights[f.Address] = f.Weight
+		if f.Picks >= lastPicks[f.Address] {
+			w.picks[f.Address] = f.Picks - lastPicks[f.Address]
+		}
+	}
+	return w.report(cur.CacheKey)
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+	roundrobin "github.com/hertz-contrib/loadbalance/round_robin"
+)
+
+func TestSkewDetector(t *testing.T) {
+	ft := loadbalanceEx.NewFairnessTracker()
+	n := loadbalanceEx.NewNotifier(firstBalancer{})
+	n.AddListener(ft)
+
+	alerts := make(chan loadbalanceEx.FairnessReport, 10)
+	d := loadbalanceEx.NewSkewDetector(ft, 0.5, 20*time.Millisecond, func(r loadbalanceEx.FairnessReport) {
+		alerts <- r
+	}, loadbalanceEx.WithCheckInterval(5*time.Millisecond), loadbalanceEx.WithMinPicks(10))
+	defer d.Close()
+
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 10, nil),
+		},
+		CacheKey: "a",
+	}
+	// every pick goes to the first instance
+	deadline := time.After(time.Second)
+	var r loadbalanceEx.FairnessReport
+loop:
+	for {
+		select {
+		case r = <-alerts:
+			break loop
+		case <-deadline:
+			t.Fatal("no skew detected")
+		default:
+			for i := 0; i < 10; i++ {
+				n.Pick(e)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	assert.DeepEqual(t, "a", r.CacheKey)
+	assert.DeepEqual(t, float64(1), r.MaxDeviation)
+	assert.DeepEqual(t, uint64(0), r.Instances[1].Picks)
+
+	// the callback is called once per skewed period
+	time.Sleep(30 * time.Millisecond)
+	assert.DeepEqual(t, 0, len(alerts))
+}
+
+func TestSkewDetectorUniform(t *testing.T) {
+	lb := roundrobin.NewRoundRobinBalancer()
+	ft := loadbalanceEx.NewFairnessTracker(loadbalanceEx.WithExpectation(loadbalanceEx.ExpectationOf(lb)))
+	n := loadbalanceEx.NewNotifier(lb)
+	n.AddListener(ft)
+
+	alerts := make(chan loadbalanceEx.FairnessReport, 10)
+	d := loadbalanceEx.NewSkewDetector(ft, 0.5, 10*time.Millisecond, func(r loadbalanceEx.FairnessReport) {
+		alerts <- r
+	}, loadbalanceEx.WithCheckInterval(5*time.Millisecond), loadbalanceEx.WithMinPicks(10))
+	defer d.Close()
+
+	// round-robin ignores the unequal weights, which is not a skew
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 90, nil),
+		},
+		CacheKey: "a",
+	}
+	for i := 0; i < 50; i++ {
+		for j := 0; j < 10; j++ {
+			n.Pick(e)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	assert.DeepEqual(t, 0, len(alerts))
+}
+
+func TestSkewDetectorZeroInterval(t *testing.T) {
+	ft := loadbalanceEx.NewFairnessTracker()
+	// falls back to the default interval instead of panicking in the background
+	d := loadbalanceEx.NewSkewDetector(ft, 0.5, time.Second, func(r loadbalanceEx.FairnessReport) {}, loadbalanceEx.WithCheckInterval(0))
+	d.Close()
+}
@@ -0,0 +1,300 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package loadbalance
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+)
+
+// SplitRule is a group of instances of a Split: the instances matching an expression over their tags,
+// and the weight of the picks it serves.
+type SplitRule struct {
+	// Name names the group, the wrapped balancer holds its state under the cache key suffixed with "#" and Name.
+	Name   string
+	Match  *TagExpr
+	Weight int
+}
+
+// Split is a Loadbalancer splitting the picks between groups of instances matching expressions over their tags,
+// e.g. 90% to `color == "blue"` and 10% to `color == "green"`, for blue/green cutovers which do not change the
+// weights in the registry. Within a group, the instance is picked by the wrapped balancer.
+type Split struct {
+	splitter
+}
+
+// NewSplit creates a Split splitting the picks of lb between rules, an instance belonging to the first rule it
+// matches. Instances matching no rule with a positive weight are not picked, unless no such rule has instances,
+// in which case lb picks among all the instances. It panics if a rule has no Match.
+func NewSplit(lb loadbalance.Loadbalancer, rules []SplitRule) *Split {
+	rules = append([]SplitRule(nil), rules...)
+	for _, r := range rules {
+		if r.Match == nil {
+			panic(fmt.Sprintf("loadbalance: NewSplit rule %q has no Match", r.Name))
+		}
+	}
+	s := &Split{splitter{lb: lb, excluded: "matches no weighted rule", key: func(ins discovery.Instance) (string, bool) {
+		for _, r := range rules {
+			if r.Match.Match(ins) {
+				return r.Name, true
+			}
+		}
+		return "", false
+	}}}
+	weights := make(map[string]int, len(rules))
+	for _, r := range rules {
+		weights[r.Name] = r.Weight
+	}
+	s.SetWeights(weights)
+	return s
+}
+
+// SetWeights replaces the weights of the rules by name, to shift traffic during a cutover.
+func (s *Split) SetWeights(weights map[string]int) {
+	s.setWeights(weights)
+}
+
+// Explain implements the Explainer interface.
+func (s *Split) Explain(e discovery.Result) Decision {
+	return s.explain(s.Name(), e)
+}
+
+// Name implements the Loadbalancer interface.
+func (s *Split) Name() string {
+	return "split"
+}
+
+// splitter splits the picks of a balancer between the groups of instances sharing a key,
+// in proportion to the weights of the keys. It implements Split and Canary.
+type splitter struct {
+	// accessed atomically, first for 64-bit alignment
+	picks uint64
+
+	lb      loadbalance.Loadbalancer
+	key     func(ins discovery.Instance) (string, bool)
+	weights atomic.Value // []splitWeight
+	// strict makes picks fail instead of picking among all the instances when no weighted group has instances
+	strict bool
+	// excluded is the reason an instance in no weighted group is not picked, for Explain
+	excluded string
+
+	mu     sync.Mutex // serializes rebalances and deletes
+	groups sync.Map   // cache key -> map[string]discovery.Result, by group key
+}
+
+// goldenRatio64 is the fractional part of the golden ratio in 64-bit fixed point.
+const goldenRatio64 = 0x9E3779B97F4A7C15
+
+type splitWeight struct {
+	key    string
+	weight int
+}
+
+func (s *splitter) setWeights(weights map[string]int) {
+	ws := make([]splitWeight, 0, len(weights))
+	for key, weight := range weights {
+		if weight > 0 {
+			ws = append(ws, splitWeight{key: key, weight: weight})
+		}
+	}
+	sort.Slice(ws, func(i, j int) bool {
+		return ws[i].key < ws[j].key
+	})
+	s.weights.Store(ws)
+}
+
+// group splits the instances of e by key.
+func (s *splitter) group(e discovery.Result) map[string]discovery.Result {
+	groups := make(map[string]discovery.Result)
+	for _, ins := range e.Instances {
+		key, ok := s.key(ins)
+		if !ok {
+			continue
+		}
+		g, ok := groups[key]
+		if !ok {
+			g = discovery.Result{CacheKey: e.CacheKey + "#" + key}
+		}
+		g.Instances = append(g.Instances, ins)
+		groups[key] = g
+	}
+	return groups
+}
+
+// groupsOf returns the groups of the cache key of e, grouping e if it was not rebalanced yet.
+func (s *splitter) groupsOf(e discovery.Result) map[string]discovery.Result {
+	if groups, ok := s.groups.Load(e.CacheKey); ok {
+		return groups.(map[string]discovery.Result)
+	}
+	groups, _ := s.groups.LoadOrStore(e.CacheKey, s.group(e))
+	return groups.(map[string]discovery.Result)
+}
+
+// total returns the total weight of the groups with instances.
+func (s *splitter) total(groups map[string]discovery.Result, ws []splitWeight) int {
+	total := 0
+	for _, w := range ws {
+		if len(groups[w.key].Instances) > 0 {
+			total += w.weight
+		}
+	}
+	return total
+}
+
+// choose returns the key of the group serving the nth pick, total being the total weight of the groups
+// with instances. The weights of the groups without instances are shared by the other ones.
+func (s *splitter) choose(groups map[string]discovery.Result, ws []splitWeight, total int, n uint64) string {
+	// the fraction of n times the golden ratio in 64-bit fixed point, scaled to total
+	hi, _ := bits.Mul64(n*goldenRatio64, uint64(total))
+	x := int(hi)
+	for _, w := range ws {
+		if len(groups[w.key].Instances) == 0 {
+			continue
+		}
+		if x < w.weight {
+			return w.key
+		}
+		x -= w.weight
+	}
+	return ""
+}
+
+// Pick implements the Loadbalancer interface.
+// Picks are spread evenly between the groups, rather than in bursts, following the golden ratio sequence.
+func (s *splitter) Pick(e discovery.Result) discovery.Instance {
+	groups := s.groupsOf(e)
+	ws := s.weights.Load().([]splitWeight)
+	total := s.total(groups, ws)
+	if total == 0 {
+		if s.strict {
+			return nil
+		}
+		return s.lb.Pick(e)
+	}
+	key := s.choose(groups, ws, total, atomic.AddUint64(&s.picks, 1))
+	if key == "" {
+		return nil
+	}
+	return s.lb.Pick(groups[key])
+}
+
+// explain returns the decision the next Pick of e would take, for the balancer named name.
+// The instances of the other groups are excluded from the next pick, as well as the instances
+// in no weighted group, which are never picked.
+func (s *splitter) explain(name string, e discovery.Result) Decision {
+	groups, ok := s.groups.Load(e.CacheKey)
+	if !ok {
+		groups = s.group(e)
+	}
+	gs := groups.(map[string]discovery.Result)
+	ws := s.weights.Load().([]splitWeight)
+	total := s.total(gs, ws)
+	if total == 0 && !s.strict {
+		d := explainAll(s.lb, e)
+		d.Balancer = name
+		return d
+	}
+	chosen := ""
+	if total > 0 {
+		chosen = s.choose(gs, ws, total, atomic.LoadUint64(&s.picks)+1)
+	}
+	d := Decision{Balancer: name, CacheKey: e.CacheKey}
+	if chosen != "" {
+		inner := explainAll(s.lb, gs[chosen])
+		d.Instance, d.Candidates = inner.Instance, inner.Candidates
+	}
+	weighted := make(map[string]bool, len(ws))
+	for _, w := range ws {
+		weighted[w.key] = true
+	}
+	for _, ins := range e.Instances {
+		addr, ok := validAddress(ins)
+		if !ok {
+			continue
+		}
+		key, ok := s.key(ins)
+		if ok && key == chosen {
+			continue
+		}
+		excluded := s.excluded
+		if ok && weighted[key] {
+			excluded = fmt.Sprintf("in group %q, the next pick goes to group %q", key, chosen)
+		}
+		d.Candidates = append(d.Candidates, Candidate{
+			Address:  addr,
+			Weight:   ins.Weight(),
+			Excluded: excluded,
+		})
+	}
+	return d
+}
+
+// Rebalance implements the Loadbalancer interface, it rebalances the groups of e and deletes the groups
+// which no longer have instances.
+func (s *splitter) Rebalance(e discovery.Result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	groups := s.group(e)
+	if old, ok := s.groups.Load(e.CacheKey); ok {
+		for key, g := range old.(map[string]discovery.Result) {
+			if _, ok := groups[key]; !ok {
+				s.lb.Delete(g.CacheKey)
+			}
+		}
+	}
+	for _, g := range groups {
+		s.lb.Rebalance(g)
+	}
+	// strict splitters never pick among all the instances, which must not be cached
+	if !s.strict {
+		s.lb.Rebalance(e)
+	}
+	s.groups.Store(e.CacheKey, groups)
+}
+
+// Prime implements the Primer interface, it primes the groups of e.
+func (s *splitter) Prime(e discovery.Result) {
+	for _, g := range s.groupsOf(e) {
+		Prime(s.lb, g)
+	}
+	if !s.strict {
+		Prime(s.lb, e)
+	}
+}
+
+// Delete implements the Loadbalancer interface, it deletes the groups of the cache key.
+func (s *splitter) Delete(cacheKey string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if groups, ok := s.groups.Load(cacheKey); ok {
+		for _, g := range groups.(map[string]discovery.Result) {
+			s.lb.Delete(g.CacheKey)
+		}
+		s.groups.Delete(cacheKey)
+	}
+	s.lb.Delete(cacheKey)
+}
+
+// Unwrap implements the Wrapper interface.
+func (s *splitter) Unwrap() loadbalance.Loadbalancer {
+	return s.lb
+}
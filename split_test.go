@@ -0,0 +1,99 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package loadbalance_test
+
+import (
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+	roundrobin "github.com/hertz-contrib/loadbalance/round_robin"
+)
+
+func TestSplit(t *testing.T) {
+	color := func(c string) map[string]string {
+		return map[string]string{"color": c}
+	}
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, color("blue")),
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 10, color("green")),
+			discovery.NewInstance("tcp", "127.0.0.1:8882", 10, color("red")),
+		},
+		CacheKey: "a",
+	}
+	s := loadbalanceEx.NewSplit(roundrobin.NewRoundRobinBalancer(), []loadbalanceEx.SplitRule{
+		{Name: "blue", Match: loadbalanceEx.MustParseTagExpr(`color == blue`), Weight: 9},
+		// the blue instance belongs to the first rule it matches
+		{Name: "green", Match: loadbalanceEx.MustParseTagExpr(`color == blue || color == green`), Weight: 1},
+	})
+	s.Rebalance(e)
+	picks := func(n int) map[string]int {
+		res := make(map[string]int)
+		for i := 0; i < n; i++ {
+			res[s.Pick(e).Address().String()]++
+		}
+		return res
+	}
+	assert.DeepEqual(t, map[string]int{"127.0.0.1:8880": 90, "127.0.0.1:8881": 10}, picks(100))
+	state, ok := loadbalanceEx.Snapshot(s, "a#green")
+	assert.True(t, ok)
+	assert.DeepEqual(t, 1, len(state.Instances))
+
+	// cutover
+	s.SetWeights(map[string]int{"green": 1})
+	assert.DeepEqual(t, map[string]int{"127.0.0.1:8881": 10}, picks(10))
+	d, ok := loadbalanceEx.Explain(s, e)
+	assert.True(t, ok)
+	assert.DeepEqual(t, "127.0.0.1:8881", d.Instance.Address().String())
+	excluded := make(map[string]string)
+	for _, c := range d.Candidates {
+		excluded[c.Address] = c.Excluded
+	}
+	assert.DeepEqual(t, map[string]string{
+		"127.0.0.1:8880": "matches no weighted rule",
+		"127.0.0.1:8881": "",
+		"127.0.0.1:8882": "matches no weighted rule",
+	}, excluded)
+	assert.DeepEqual(t, "split", s.Name())
+}
+
+func TestSplitNarrow(t *testing.T) {
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, map[string]string{"color": "blue"}),
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 10, map[string]string{"color": "green"}),
+		},
+		CacheKey: "a",
+	}
+	s := loadbalanceEx.NewSplit(firstBalancer{}, []loadbalanceEx.SplitRule{
+		{Name: "blue", Match: loadbalanceEx.MustParseTagExpr(`color == blue`), Weight: 9999},
+		{Name: "green", Match: loadbalanceEx.MustParseTagExpr(`color == green`), Weight: 1},
+	})
+	green := 0
+	for i := 0; i < 100000; i++ {
+		if s.Pick(e).Address().String() == "127.0.0.1:8881" {
+			green++
+		}
+	}
+	assert.Assert(t, green >= 9 && green <= 11, green)
+
+	defer func() {
+		assert.True(t, recover() != nil)
+	}()
+	loadbalanceEx.NewSplit(firstBalancer{}, []loadbalanceEx.SplitRule{{Name: "blue", Weight: 1}})
+}
@@ -0,0 +1,152 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+)
+
+// StaleInstance is an instance with a positive weight that has not been picked for a while.
+type StaleInstance struct {
+	CacheKey string `json:"cache_key"`
+	Address  string `json:"address"`
+	Weight   int    `json:"weight"`
+	// LastPick is the time the instance was last picked, zero if it never was.
+	LastPick time.Time `json:"last_pick"`
+	// Since is the time the instance was first seen.
+	Since time.Time `json:"since"`
+}
+
+type stalenessEntry struct {
+	lastPick int64 // unix nano, accessed atomically
+	weight   int
+	since    time.Time
+}
+
+// StalenessTracker is a Listener tracking the last time each instance was picked,
+// to find instances which are never picked despite their positive weight,
+// a sign of scheduling bugs or pathological distributions.
+type StalenessTracker struct {
+	mu      sync.RWMutex
+	entries map[string]map[string]*stalenessEntry
+}
+
+// NewStalenessTracker creates a StalenessTracker.
+func NewStalenessTracker() *StalenessTracker {
+	return &StalenessTracker{
+		entries: make(map[string]map[string]*stalenessEntry),
+	}
+}
+
+// OnPick implements the Listener interface.
+func (st *StalenessTracker) OnPick(e PickEvent) {
+	if e.Instance == nil {
+		return
+	}
+	now := time.Now()
+	addr := e.Instance.Address().String()
+	st.mu.RLock()
+	entry, ok := st.entries[e.Result.CacheKey][addr]
+	st.mu.RUnlock()
+	if !ok {
+		st.mu.Lock()
+		if _, ok = st.entries[e.Result.CacheKey]; !ok {
+			st.setInstances(e.Result.CacheKey, e.Result.Instances, now)
+		}
+		entry, ok = st.entries[e.Result.CacheKey][addr]
+		st.mu.Unlock()
+		if !ok {
+			return
+		}
+	}
+	atomic.StoreInt64(&entry.lastPick, now.UnixNano())
+}
+
+// OnRebalance implements the Listener interface.
+func (st *StalenessTracker) OnRebalance(e RebalanceEvent) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.setInstances(e.Result.CacheKey, e.Result.Instances, time.Now())
+}
+
+// OnDelete implements the Listener interface.
+func (st *StalenessTracker) OnDelete(e DeleteEvent) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	delete(st.entries, e.CacheKey)
+}
+
+// setInstances replaces the instances of cacheKey, keeping what is known about the remaining ones.
+func (st *StalenessTracker) setInstances(cacheKey string, instances []discovery.Instance, now time.Time) {
+	old := st.entries[cacheKey]
+	entries := make(map[string]*stalenessEntry, len(instances))
+	for _, ins := range instances {
+		addr, ok := validAddress(ins)
+		if !ok {
+			continue
+		}
+		entry, ok := old[addr]
+		if !ok {
+			entry = &stalenessEntry{since: now}
+		}
+		entry.weight = ins.Weight()
+		entries[addr] = entry
+	}
+	st.entries[cacheKey] = entries
+}
+
+// Stale returns the instances with a positive weight which have not been picked for d,
+// sorted by cache key and address. Instances seen for less than d are not reported.
+func (st *StalenessTracker) Stale(d time.Duration) []StaleInstance {
+	deadline := time.Now().Add(-d)
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	var res []StaleInstance
+	for cacheKey, entries := range st.entries {
+		for addr, entry := range entries {
+			if entry.weight <= 0 || entry.since.After(deadline) {
+				continue
+			}
+			var lastPick time.Time
+			if nano := atomic.LoadInt64(&entry.lastPick); nano != 0 {
+				lastPick = time.Unix(0, nano)
+				if lastPick.After(deadline) {
+					continue
+				}
+			}
+			res = append(res, StaleInstance{
+				CacheKey: cacheKey,
+				Address:  addr,
+				Weight:   entry.weight,
+				LastPick: lastPick,
+				Since:    entry.since,
+			})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].CacheKey != res[j].CacheKey {
+			return res[i].CacheKey < res[j].CacheKey
+		}
+		return res[i].Address < res[j].Address
+	})
+	return res
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+)
+
+// firstBalancer always picks the first instance.
+type firstBalancer struct{}
+
+func (firstBalancer) Pick(e discovery.Result) discovery.Instance {
+	if len(e.Instances) == 0 {
+		return nil
+	}
+	return e.Instances[0]
+}
+
+func (firstBalancer) Rebalance(discovery.Result) {}
+
+func (firstBalancer) Delete(string) {}
+
+func (firstBalancer) Name() string { return "first" }
+
+var _ loadbalance.Loadbalancer = firstBalancer{}
+
+func TestStalenessTracker(t *testing.T) {
+	st := loadbalanceEx.NewStalenessTracker()
+	n := loadbalanceEx.NewNotifier(firstBalancer{})
+	n.AddListener(st)
+
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 10, nil),
+		},
+		CacheKey: "a",
+	}
+	n.Pick(e)
+	// instances seen for less than the duration are not reported
+	assert.DeepEqual(t, 0, len(st.Stale(time.Minute)))
+
+	time.Sleep(10 * time.Millisecond)
+	n.Pick(e)
+	stale := st.Stale(5 * time.Millisecond)
+	assert.DeepEqual(t, 1, len(stale))
+	assert.DeepEqual(t, "a", stale[0].CacheKey)
+	assert.DeepEqual(t, "127.0.0.1:8881", stale[0].Address)
+	assert.Assert(t, stale[0].LastPick.IsZero())
+
+	time.Sleep(10 * time.Millisecond)
+	stale = st.Stale(5 * time.Millisecond)
+	assert.DeepEqual(t, 2, len(stale))
+	assert.False(t, stale[0].LastPick.IsZero())
+
+	// the removed instance is forgotten
+	e.Instances = e.Instances[:1]
+	n.Rebalance(e)
+	assert.DeepEqual(t, 1, len(st.Stale(5*time.Millisecond)))
+
+	n.Delete("a")
+	assert.DeepEqual(t, 0, len(st.Stale(0)))
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance
+
+import (
+	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+)
+
+// InstanceState is the state of an instance held by a balancer.
+type InstanceState struct {
+	Address string `json:"address"`
+	Weight  int    `json:"weight"`
+	// Picks is the number of times the instance was picked since the state was built.
+	Picks uint64 `json:"picks"`
+}
+
+// State is the state a balancer holds for a cache key.
+type State struct {
+	CacheKey  string          `json:"cache_key"`
+	Instances []InstanceState `json:"instances"`
+}
+
+// StateProvider is implemented by balancers able to report their state.
+type StateProvider interface {
+	// States returns a copy of the state held for every cache key.
+	States() []State
+
+	// Snapshot returns a copy of the state held for cacheKey,
+	// or false if the balancer holds nothing for it.
+	Snapshot(cacheKey string) (State, bool)
+}
+
+// Ranger is implemented by balancers able to iterate the state of a cache key without copying it.
+type Ranger interface {
+	// Range calls fn for every instance of a consistent snapshot of the state held for cacheKey,
+	// until fn returns false. It returns false if the balancer holds nothing for cacheKey.
+	Range(cacheKey string, fn func(InstanceState) bool) bool
+}
+
+// Wrapper is implemented by balancers wrapping another Loadbalancer.
+type Wrapper interface {
+	// Unwrap returns the wrapped Loadbalancer.
+	Unwrap() loadbalance.Loadbalancer
+}
+
+// States returns the states held by lb, or by the balancer it wraps.
+// It returns nil if no balancer in the chain implements StateProvider.
+func States(lb loadbalance.Loadbalancer) []State {
+	sp, ok := stateProvider(lb)
+	if !ok {
+		return nil
+	}
+	return sp.States()
+}
+
+// Snapshot returns the state held for cacheKey by lb, or by the balancer it wraps.
+// It returns false if no balancer in the chain implements StateProvider or holds nothing for cacheKey.
+func Snapshot(lb loadbalance.Loadbalancer, cacheKey string) (State, bool) {
+	sp, ok := stateProvider(lb)
+	if !ok {
+		return State{}, false
+	}
+	return sp.Snapshot(cacheKey)
+}
+
+// Range calls fn for every instance of the state held for cacheKey by lb, or by the balancer it wraps,
+// until fn returns false. Balancers implementing StateProvider but not Ranger are iterated over a snapshot.
+// It returns false if no balancer in the chain implements either or holds nothing for cacheKey.
+func Range(lb loadbalance.Loadbalancer, cacheKey string, fn func(InstanceState) bool) bool {
+	found := find(lb, func(lb loadbalance.Loadbalancer) bool {
+		switch lb.(type) {
+		case Ranger, StateProvider:
+			return true
+		}
+		return false
+	})
+	switch b := found.(type) {
+	case Ranger:
+		return b.Range(cacheKey, fn)
+	case StateProvider:
+		state, ok := b.Snapshot(cacheKey)
+		if !ok {
+			return false
+		}
+		for _, ins := range state.Instances {
+			if !fn(ins) {
+				break
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func stateProvider(lb loadbalance.Loadbalancer) (StateProvider, bool) {
+	sp, ok := find(lb, func(lb loadbalance.Loadbalancer) bool {
+		_, ok := lb.(StateProvider)
+		return ok
+	}).(StateProvider)
+	return sp, ok
+}
+
+// find returns the first balancer of the chain wrapped by lb, lb included, for which match returns true.
+// It returns nil if there is none.
+func find(lb loadbalance.Loadbalancer, match func(lb loadbalance.Loadbalancer) bool) loadbalance.Loadbalancer {
+	for lb != nil {
+		if match(lb) {
+			return lb
+		}
+		w, ok := lb.(Wrapper)
+		if !ok {
+			return nil
+		}
+		lb = w.Unwrap()
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package loadbalance_test
+
+import (
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+	roundrobin "github.com/hertz-contrib/loadbalance/round_robin"
+)
+
+// snapshotBalancer implements StateProvider but not Ranger.
+type snapshotBalancer struct {
+	firstBalancer
+	state loadbalanceEx.State
+}
+
+func (b snapshotBalancer) States() []loadbalanceEx.State {
+	return []loadbalanceEx.State{b.state}
+}
+
+func (b snapshotBalancer) Snapshot(cacheKey string) (loadbalanceEx.State, bool) {
+	return b.state, cacheKey == b.state.CacheKey
+}
+
+func TestRange(t *testing.T) {
+	lb := wrapped{roundrobin.NewRoundRobinBalancer()}
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 10, nil),
+		},
+		CacheKey: "a",
+	}
+	lb.Rebalance(e)
+	lb.Pick(e)
+
+	var instances []loadbalanceEx.InstanceState
+	collect := func(ins loadbalanceEx.InstanceState) bool {
+		instances = append(instances, ins)
+		return true
+	}
+	assert.True(t, loadbalanceEx.Range(lb, "a", collect))
+	state, _ := loadbalanceEx.Snapshot(lb, "a")
+	assert.DeepEqual(t, state.Instances, instances)
+
+	// stops when fn returns false
+	n := 0
+	assert.True(t, loadbalanceEx.Range(lb, "a", func(loadbalanceEx.InstanceState) bool {
+		n++
+		return false
+	}))
+	assert.DeepEqual(t, 1, n)
+	assert.False(t, loadbalanceEx.Range(lb, "b", collect))
+
+	// balancers implementing StateProvider only are iterated over a snapshot
+	instances = nil
+	sb := snapshotBalancer{state: loadbalanceEx.State{
+		CacheKey:  "a",
+		Instances: []loadbalanceEx.InstanceState{{Address: "127.0.0.1:8880", Weight: 10}},
+	}}
+	assert.True(t, loadbalanceEx.Range(wrapped{sb}, "a", collect))
+	assert.DeepEqual(t, sb.state.Instances, instances)
+	assert.False(t, loadbalanceEx.Range(sb, "b", collect))
+
+	// balancers without state
+	assert.False(t, loadbalanceEx.Range(loadbalance.NewWeightedBalancer(), "a", collect))
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package statsd
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+// Config is the declarative configuration of the statsd balancer,
+// zero fields take their default value.
+type Config struct {
+	// Address is the UDP address of the StatsD server, "127.0.0.1:8125" by default.
+	Address string `json:"address,omitempty" yaml:"address,omitempty"`
+	// Prefix is the prefix of the metric names, "hertz.loadbalance." by default.
+	Prefix string `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+	// FlushInterval is the interval at which aggregated metrics are sent, one second by default.
+	FlushInterval time.Duration `json:"flush_interval,omitempty" yaml:"flush_interval,omitempty"`
+	// TimingSampleRate is the fraction of picks whose duration is sent, 0.01 by default.
+	// It is a pointer so that 0 disables the timings.
+	TimingSampleRate *float64 `json:"timing_sample_rate,omitempty" yaml:"timing_sample_rate,omitempty"`
+	// MaxPacketSize is the maximum size of the UDP packets, 1432 bytes by default.
+	MaxPacketSize int `json:"max_packet_size,omitempty" yaml:"max_packet_size,omitempty"`
+}
+
+// Validate returns an error if the configuration is invalid.
+func (c Config) Validate() error {
+	if c.Address != "" {
+		if _, _, err := net.SplitHostPort(c.Address); err != nil {
+			return fmt.Errorf("statsd: invalid address: %w", err)
+		}
+	}
+	if c.FlushInterval < 0 {
+		return fmt.Errorf("statsd: negative flush interval %v", c.FlushInterval)
+	}
+	if c.TimingSampleRate != nil && (*c.TimingSampleRate < 0 || *c.TimingSampleRate > 1) {
+		return fmt.Errorf("statsd: timing sample rate %v is not between 0 and 1", *c.TimingSampleRate)
+	}
+	if c.MaxPacketSize < 0 {
+		return fmt.Errorf("statsd: negative max packet size %d", c.MaxPacketSize)
+	}
+	return nil
+}
+
+// WithDefaults returns the configuration with the default value of its zero fields.
+func (c Config) WithDefaults() Config {
+	def := newConfig(nil)
+	if c.Address == "" {
+		c.Address = def.address
+	}
+	if c.Prefix == "" {
+		c.Prefix = def.prefix
+	}
+	if c.FlushInterval == 0 {
+		c.FlushInterval = def.flushInterval
+	}
+	if c.TimingSampleRate == nil {
+		rate := def.timingSampleRate
+		c.TimingSampleRate = &rate
+	}
+	if c.MaxPacketSize == 0 {
+		c.MaxPacketSize = def.maxPacketSize
+	}
+	return c
+}
+
+// Options returns the options equivalent to the configuration.
+func (c Config) Options() []Option {
+	var opts []Option
+	if c.Address != "" {
+		opts = append(opts, WithAddress(c.Address))
+	}
+	if c.Prefix != "" {
+		opts = append(opts, WithPrefix(c.Prefix))
+	}
+	if c.FlushInterval > 0 {
+		opts = append(opts, WithFlushInterval(c.FlushInterval))
+	}
+	if c.TimingSampleRate != nil {
+		opts = append(opts, WithTimingSampleRate(*c.TimingSampleRate))
+	}
+	if c.MaxPacketSize > 0 {
+		opts = append(opts, WithMaxPacketSize(c.MaxPacketSize))
+	}
+	return opts
+}
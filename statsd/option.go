@@ -0,0 +1,105 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package statsd
+
+import (
+	"time"
+
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+)
+
+// Option is the only struct that can be used to set config.
+type Option interface {
+	apply(cfg *config)
+}
+
+type option func(cfg *config)
+
+func (fn option) apply(cfg *config) {
+	fn(cfg)
+}
+
+type config struct {
+	address          string
+	prefix           string
+	flushInterval    time.Duration
+	timingSampleRate float64
+	maxPacketSize    int
+	labels           loadbalanceEx.MetricLabels
+}
+
+func newConfig(opts []Option) *config {
+	cfg := &config{
+		address:          "127.0.0.1:8125",
+		prefix:           "hertz.loadbalance.",
+		flushInterval:    time.Second,
+		timingSampleRate: 0.01,
+		maxPacketSize:    1432,
+		labels:           loadbalanceEx.DefaultMetricLabels,
+	}
+	for _, opt := range opts {
+		opt.apply(cfg)
+	}
+	if cfg.flushInterval <= 0 {
+		cfg.flushInterval = time.Second
+	}
+	return cfg
+}
+
+// WithAddress sets the UDP address of the StatsD server, "127.0.0.1:8125" by default.
+func WithAddress(address string) Option {
+	return option(func(cfg *config) {
+		cfg.address = address
+	})
+}
+
+// WithPrefix sets the prefix of the metric names, "hertz.loadbalance." by default.
+func WithPrefix(prefix string) Option {
+	return option(func(cfg *config) {
+		cfg.prefix = prefix
+	})
+}
+
+// WithFlushInterval sets the interval at which aggregated metrics are sent, one second by default
+// or if interval is not positive.
+func WithFlushInterval(interval time.Duration) Option {
+	return option(func(cfg *config) {
+		cfg.flushInterval = interval
+	})
+}
+
+// WithTimingSampleRate sets the fraction of picks whose duration is sent, 0.01 by default.
+func WithTimingSampleRate(rate float64) Option {
+	return option(func(cfg *config) {
+		cfg.timingSampleRate = rate
+	})
+}
+
+// WithMaxPacketSize sets the maximum size of the UDP packets, 1432 bytes by default
+// which fits the usual MTU of 1500 bytes.
+func WithMaxPacketSize(size int) Option {
+	return option(func(cfg *config) {
+		cfg.maxPacketSize = size
+	})
+}
+
+// WithLabels sets the tags of the metrics, loadbalance.DefaultMetricLabels by default.
+func WithLabels(labels loadbalanceEx.MetricLabels) Option {
+	return option(func(cfg *config) {
+		cfg.labels = labels
+	})
+}
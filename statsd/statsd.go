@@ -0,0 +1,272 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package statsd sends the metrics of a load balancer to a StatsD server
+// using the DogStatsD tag extension, understood by Datadog and Telegraf.
+package statsd
+
+import (
+	"bytes"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+)
+
+// maxTimings bounds the pick durations held between two flushes, the further ones are dropped.
+const maxTimings = 1000
+
+type metricKey struct {
+	name string
+	tags string
+}
+
+// Balancer wraps a Loadbalancer and sends its picks, pick latency, rebalances
+// and deletes to a StatsD server.
+//
+// Counters are aggregated in memory and sent every flush interval, pick durations are sampled.
+// Counters not incremented for a flush interval, and those of deleted cache keys, are dropped.
+type Balancer struct {
+	lb   loadbalance.Loadbalancer
+	cfg  *config
+	conn net.Conn
+
+	counters sync.Map // metricKey -> *int64
+
+	mu      sync.Mutex
+	gauges  map[metricKey]int64
+	timings []string
+	// lines of the dropped counters, sent by the next flush
+	pending []string
+
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewBalancer wraps lb and starts sending its metrics to a StatsD server.
+// Close must be called to stop sending them.
+func NewBalancer(lb loadbalance.Loadbalancer, opts ...Option) (*Balancer, error) {
+	cfg := newConfig(opts)
+	conn, err := net.Dial("udp", cfg.address)
+	if err != nil {
+		return nil, err
+	}
+	b := &Balancer{
+		lb:     lb,
+		cfg:    cfg,
+		conn:   conn,
+		gauges: make(map[metricKey]int64),
+		done:   make(chan struct{}),
+	}
+	b.wg.Add(1)
+	go b.loop()
+	return b, nil
+}
+
+// Pick implements the Loadbalancer interface.
+func (b *Balancer) Pick(e discovery.Result) discovery.Instance {
+	start := time.Now()
+	ins := b.lb.Pick(e)
+	if b.cfg.timingSampleRate > 0 && rand.Float64() < b.cfg.timingSampleRate {
+		b.timing("pick_duration", time.Since(start), b.tags(e.CacheKey, nil))
+	}
+	if ins == nil {
+		b.count("pick_failures", b.tags(e.CacheKey, nil)+",reason:"+loadbalanceEx.FailureReason(b.lb, e))
+		return nil
+	}
+	b.count("picks", b.tags(e.CacheKey, ins))
+	return ins
+}
+
+// Rebalance implements the Loadbalancer interface.
+func (b *Balancer) Rebalance(e discovery.Result) {
+	b.lb.Rebalance(e)
+	// the instances with a malformed address are never picked
+	instances, _ := loadbalanceEx.SanitizeInstances(e.Instances)
+	t := b.tags(e.CacheKey, nil)
+	b.count("rebalances", t)
+	b.gauge("instances", int64(len(instances)), t)
+}
+
+// Delete implements the Loadbalancer interface.
+func (b *Balancer) Delete(cacheKey string) {
+	b.lb.Delete(cacheKey)
+	t := b.tags(cacheKey, nil)
+	b.dropCounters(t)
+	b.count("deletes", t)
+	b.gauge("instances", 0, t)
+}
+
+// Name implements the Loadbalancer interface.
+func (b *Balancer) Name() string {
+	return b.lb.Name()
+}
+
+// Unwrap returns the wrapped Loadbalancer.
+func (b *Balancer) Unwrap() loadbalance.Loadbalancer {
+	return b.lb
+}
+
+// Close sends the pending metrics and stops sending metrics.
+func (b *Balancer) Close() error {
+	var err error
+	b.closeOnce.Do(func() {
+		close(b.done)
+		b.wg.Wait()
+		err = b.conn.Close()
+	})
+	return err
+}
+
+func (b *Balancer) count(name, tags string) {
+	key := metricKey{name: name, tags: tags}
+	v, ok := b.counters.Load(key)
+	if !ok {
+		v, _ = b.counters.LoadOrStore(key, new(int64))
+	}
+	atomic.AddInt64(v.(*int64), 1)
+}
+
+// dropCounters drops the counters of the tags of a cache key, their pending counts are sent by the next flush.
+// Cache keys whose labels are the same share their counters, which are then dropped together.
+func (b *Balancer) dropCounters(tags string) {
+	var lines []string
+	b.counters.Range(func(key, value interface{}) bool {
+		k := key.(metricKey)
+		if k.tags != tags && !strings.HasPrefix(k.tags, tags+",") {
+			return true
+		}
+		b.counters.Delete(key)
+		if v := atomic.SwapInt64(value.(*int64), 0); v != 0 {
+			lines = append(lines, b.counterLine(k, v))
+		}
+		return true
+	})
+	if len(lines) == 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending = append(b.pending, lines...)
+}
+
+func (b *Balancer) counterLine(k metricKey, v int64) string {
+	return b.cfg.prefix + k.name + ":" + strconv.FormatInt(v, 10) + "|c|#" + k.tags
+}
+
+func (b *Balancer) gauge(name string, value int64, tags string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.gauges[metricKey{name: name, tags: tags}] = value
+}
+
+func (b *Balancer) timing(name string, d time.Duration, tags string) {
+	line := b.cfg.prefix + name + ":" + strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64) +
+		"|ms|@" + strconv.FormatFloat(b.cfg.timingSampleRate, 'f', -1, 64) + "|#" + tags
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.timings) < maxTimings {
+		b.timings = append(b.timings, line)
+	}
+}
+
+func (b *Balancer) loop() {
+	defer b.wg.Done()
+	ticker := time.NewTicker(b.cfg.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			b.flush()
+		case <-b.done:
+			b.flush()
+			return
+		}
+	}
+}
+
+func (b *Balancer) flush() {
+	var lines []string
+	b.counters.Range(func(key, value interface{}) bool {
+		k := key.(metricKey)
+		v := atomic.SwapInt64(value.(*int64), 0)
+		if v == 0 {
+			// not incremented since the previous flush, a count racing with the delete is sent now
+			b.counters.Delete(key)
+			v = atomic.SwapInt64(value.(*int64), 0)
+		}
+		if v != 0 {
+			lines = append(lines, b.counterLine(k, v))
+		}
+		return true
+	})
+	b.mu.Lock()
+	lines = append(lines, b.pending...)
+	b.pending = nil
+	for k, v := range b.gauges {
+		lines = append(lines, b.cfg.prefix+k.name+":"+strconv.FormatInt(v, 10)+"|g|#"+k.tags)
+	}
+	b.gauges = make(map[metricKey]int64)
+	lines = append(lines, b.timings...)
+	b.timings = nil
+	b.mu.Unlock()
+
+	var buf bytes.Buffer
+	for _, line := range lines {
+		if buf.Len() > 0 && buf.Len()+1+len(line) > b.cfg.maxPacketSize {
+			_, _ = b.conn.Write(buf.Bytes())
+			buf.Reset()
+		}
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(line)
+	}
+	if buf.Len() > 0 {
+		_, _ = b.conn.Write(buf.Bytes())
+	}
+}
+
+var tagReplacer = strings.NewReplacer("|", "_", ",", "_", "#", "_", "\n", "_")
+
+// tags formats the labels of cacheKey, and of ins if not nil, as DogStatsD tags.
+func (b *Balancer) tags(cacheKey string, ins discovery.Instance) string {
+	var sb strings.Builder
+	sb.WriteString("balancer:")
+	sb.WriteString(tagReplacer.Replace(b.lb.Name()))
+	writeTags(&sb, b.cfg.labels.Names, b.cfg.labels.ValuesOf(cacheKey))
+	if ins != nil {
+		writeTags(&sb, b.cfg.labels.InstanceNames, b.cfg.labels.InstanceValuesOf(ins))
+	}
+	return sb.String()
+}
+
+func writeTags(sb *strings.Builder, names, values []string) {
+	for i, name := range names {
+		sb.WriteByte(',')
+		sb.WriteString(name)
+		sb.WriteByte(':')
+		sb.WriteString(tagReplacer.Replace(values[i]))
+	}
+}
@@ -0,0 +1,164 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package statsd
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	roundrobin "github.com/hertz-contrib/loadbalance/round_robin"
+)
+
+func TestBalancer(t *testing.T) {
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer server.Close()
+
+	lb, err := NewBalancer(roundrobin.NewRoundRobinBalancer(),
+		WithAddress(server.LocalAddr().String()),
+		WithFlushInterval(time.Hour),
+		WithTimingSampleRate(1),
+		WithMaxPacketSize(64),
+	)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, "round_robin", lb.Name())
+
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 10, nil),
+		},
+		CacheKey: "a|b",
+	}
+	lb.Rebalance(e)
+	for i := 0; i < 4; i++ {
+		lb.Pick(e)
+	}
+	lb.Pick(discovery.Result{CacheKey: "c"})
+	lb.Delete("c")
+	assert.Nil(t, lb.Close())
+	assert.Nil(t, lb.Close())
+
+	var lines []string
+	buf := make([]byte, 1500)
+	_ = server.SetReadDeadline(time.Now().Add(time.Second))
+	for {
+		n, _, err := server.ReadFrom(buf)
+		if err != nil {
+			break
+		}
+		assert.Assert(t, n <= 64 || !strings.Contains(string(buf[:n]), "\n"))
+		lines = append(lines, strings.Split(string(buf[:n]), "\n")...)
+	}
+	var timings int
+	var others []string
+	for _, line := range lines {
+		if strings.HasPrefix(line, "hertz.loadbalance.pick_duration:") {
+			assert.Assert(t, strings.Contains(line, "|ms|@1|#balancer:round_robin,cache_key:"), line)
+			timings++
+			continue
+		}
+		others = append(others, line)
+	}
+	sort.Strings(others)
+	assert.DeepEqual(t, 5, timings)
+	assert.DeepEqual(t, []string{
+		"hertz.loadbalance.deletes:1|c|#balancer:round_robin,cache_key:c",
+		"hertz.loadbalance.instances:0|g|#balancer:round_robin,cache_key:c",
+		"hertz.loadbalance.instances:2|g|#balancer:round_robin,cache_key:a_b",
+		"hertz.loadbalance.pick_failures:1|c|#balancer:round_robin,cache_key:c,reason:no_instances",
+		"hertz.loadbalance.picks:2|c|#balancer:round_robin,cache_key:a_b,address:127.0.0.1:8880",
+		"hertz.loadbalance.picks:2|c|#balancer:round_robin,cache_key:a_b,address:127.0.0.1:8881",
+		"hertz.loadbalance.rebalances:1|c|#balancer:round_robin,cache_key:a_b",
+	}, others)
+}
+
+func TestConfig(t *testing.T) {
+	assert.Nil(t, Config{}.Validate())
+	assert.NotNil(t, Config{Address: "127.0.0.1"}.Validate())
+	assert.NotNil(t, Config{FlushInterval: -time.Second}.Validate())
+	rate := 1.5
+	assert.NotNil(t, Config{TimingSampleRate: &rate}.Validate())
+	assert.NotNil(t, Config{MaxPacketSize: -1}.Validate())
+
+	rate = 0
+	cfg := Config{Address: "127.0.0.1:9125", FlushInterval: time.Minute, TimingSampleRate: &rate}
+	assert.Nil(t, cfg.Validate())
+	def := cfg.WithDefaults()
+	assert.DeepEqual(t, "127.0.0.1:9125", def.Address)
+	assert.DeepEqual(t, "hertz.loadbalance.", def.Prefix)
+	assert.DeepEqual(t, 1432, def.MaxPacketSize)
+	c := newConfig(cfg.Options())
+	assert.DeepEqual(t, "127.0.0.1:9125", c.address)
+	assert.DeepEqual(t, "hertz.loadbalance.", c.prefix)
+	assert.DeepEqual(t, time.Minute, c.flushInterval)
+	assert.DeepEqual(t, float64(0), c.timingSampleRate)
+}
+
+func TestBoundedMemory(t *testing.T) {
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer server.Close()
+
+	lb, err := NewBalancer(roundrobin.NewRoundRobinBalancer(),
+		WithAddress(server.LocalAddr().String()),
+		WithFlushInterval(0),
+		WithTimingSampleRate(1),
+	)
+	assert.Nil(t, err)
+	defer lb.Close()
+	assert.DeepEqual(t, time.Second, lb.cfg.flushInterval)
+	counters := func() int {
+		n := 0
+		lb.counters.Range(func(key, value interface{}) bool {
+			n++
+			return true
+		})
+		return n
+	}
+
+	e := discovery.Result{
+		Instances: []discovery.Instance{discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil)},
+		CacheKey:  "a",
+	}
+	for i := 0; i < 2*maxTimings; i++ {
+		lb.Pick(e)
+	}
+	lb.mu.Lock()
+	assert.DeepEqual(t, maxTimings, len(lb.timings))
+	lb.mu.Unlock()
+
+	// the counters of a deleted cache key are dropped, their counts are sent by the next flush
+	lb.Pick(discovery.Result{Instances: e.Instances, CacheKey: "b"})
+	assert.DeepEqual(t, 2, counters())
+	lb.Delete("a")
+	assert.DeepEqual(t, 2, counters())
+	lb.mu.Lock()
+	assert.DeepEqual(t, []string{"hertz.loadbalance.picks:2000|c|#balancer:round_robin,cache_key:a,address:127.0.0.1:8880"}, lb.pending)
+	lb.mu.Unlock()
+
+	// counters not incremented for a flush interval are dropped
+	lb.flush()
+	assert.DeepEqual(t, 2, counters())
+	lb.flush()
+	assert.DeepEqual(t, 0, counters())
+}
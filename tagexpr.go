@@ -0,0 +1,253 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package loadbalance
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+)
+
+// TagExpr is a boolean expression over the tags of an instance, see ParseTagExpr.
+type TagExpr struct {
+	src  string
+	root tagNode
+}
+
+// ParseTagExpr parses a boolean expression over the tags of an instance, such as
+//
+//	color == "blue" && (zone == "a" || zone == "b") && !draining
+//
+// Comparisons use == and != with a quoted or a bare value, a tag alone is true when it is set and not empty,
+// and the comparisons are combined with !, && and || and parentheses, && binding tighter than ||.
+func ParseTagExpr(s string) (*TagExpr, error) {
+	p := &tagParser{src: s}
+	p.next()
+	root, err := p.or()
+	if err == nil && p.tok.kind != tagEOF {
+		err = p.errorf("unexpected %s", p.tok)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &TagExpr{src: s, root: root}, nil
+}
+
+// MustParseTagExpr is the same as ParseTagExpr but panics if s is invalid.
+func MustParseTagExpr(s string) *TagExpr {
+	x, err := ParseTagExpr(s)
+	if err != nil {
+		panic(err)
+	}
+	return x
+}
+
+// Match returns whether the tags of ins satisfy the expression.
+func (x *TagExpr) Match(ins discovery.Instance) bool {
+	return x.root.match(ins)
+}
+
+// String returns the source of the expression.
+func (x *TagExpr) String() string {
+	return x.src
+}
+
+type tagNode interface {
+	match(ins discovery.Instance) bool
+}
+
+type (
+	tagSet   struct{ key string }
+	tagEqual struct {
+		key, value string
+		negate     bool
+	}
+	tagNot struct{ x tagNode }
+	tagAnd struct{ x, y tagNode }
+	tagOr  struct{ x, y tagNode }
+)
+
+func (n tagSet) match(ins discovery.Instance) bool {
+	v, ok := ins.Tag(n.key)
+	return ok && v != ""
+}
+
+func (n tagEqual) match(ins discovery.Instance) bool {
+	v, _ := ins.Tag(n.key)
+	return (v == n.value) != n.negate
+}
+
+func (n tagNot) match(ins discovery.Instance) bool { return !n.x.match(ins) }
+func (n tagAnd) match(ins discovery.Instance) bool { return n.x.match(ins) && n.y.match(ins) }
+func (n tagOr) match(ins discovery.Instance) bool  { return n.x.match(ins) || n.y.match(ins) }
+
+type tagTokenKind int
+
+const (
+	tagEOF tagTokenKind = iota
+	tagIdent
+	tagString
+	tagOp
+)
+
+type tagToken struct {
+	kind tagTokenKind
+	text string
+	pos  int
+}
+
+func (t tagToken) String() string {
+	if t.kind == tagEOF {
+		return "end of expression"
+	}
+	return strconv.Quote(t.text)
+}
+
+type tagParser struct {
+	src string
+	pos int
+	tok tagToken
+	err error
+}
+
+func (p *tagParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("loadbalance: invalid tag expression %q at %d: %s", p.src, p.tok.pos, fmt.Sprintf(format, args...))
+}
+
+// next reads the next token into p.tok, recording the errors of the lexer in p.err.
+func (p *tagParser) next() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos == len(p.src) {
+		p.tok = tagToken{kind: tagEOF, pos: start}
+		return
+	}
+	rest := p.src[p.pos:]
+	for _, op := range []string{"==", "!=", "&&", "||", "!", "(", ")"} {
+		if strings.HasPrefix(rest, op) {
+			p.pos += len(op)
+			p.tok = tagToken{kind: tagOp, text: op, pos: start}
+			return
+		}
+	}
+	if rest[0] == '"' {
+		end := 1
+		for end < len(rest) && rest[end] != '"' {
+			if rest[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(rest) {
+			p.tok = tagToken{kind: tagEOF, pos: start}
+			p.err = p.errorf("unterminated string")
+			p.pos = len(p.src)
+			return
+		}
+		s, err := strconv.Unquote(rest[:end+1])
+		if err != nil {
+			p.tok = tagToken{kind: tagEOF, pos: start}
+			p.err = p.errorf("invalid string %s", rest[:end+1])
+			p.pos = len(p.src)
+			return
+		}
+		p.pos += end + 1
+		p.tok = tagToken{kind: tagString, text: s, pos: start}
+		return
+	}
+	for p.pos < len(p.src) && isTagChar(p.src[p.pos]) {
+		p.pos++
+	}
+	if p.pos == start {
+		p.tok = tagToken{kind: tagEOF, pos: start}
+		p.err = p.errorf("unexpected character %q", p.src[start])
+		p.pos = len(p.src)
+		return
+	}
+	p.tok = tagToken{kind: tagIdent, text: p.src[start:p.pos], pos: start}
+}
+
+func isTagChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '_' || c == '-' || c == '.' || c == '/' || c == ':'
+}
+
+func (p *tagParser) or() (tagNode, error) {
+	x, err := p.and()
+	for err == nil && p.tok.kind == tagOp && p.tok.text == "||" {
+		p.next()
+		var y tagNode
+		y, err = p.and()
+		x = tagOr{x, y}
+	}
+	return x, err
+}
+
+func (p *tagParser) and() (tagNode, error) {
+	x, err := p.unary()
+	for err == nil && p.tok.kind == tagOp && p.tok.text == "&&" {
+		p.next()
+		var y tagNode
+		y, err = p.unary()
+		x = tagAnd{x, y}
+	}
+	return x, err
+}
+
+func (p *tagParser) unary() (tagNode, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	switch {
+	case p.tok.kind == tagOp && p.tok.text == "!":
+		p.next()
+		x, err := p.unary()
+		return tagNot{x}, err
+	case p.tok.kind == tagOp && p.tok.text == "(":
+		p.next()
+		x, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tagOp || p.tok.text != ")" {
+			return nil, p.errorf("expected ) instead of %s", p.tok)
+		}
+		p.next()
+		return x, p.err
+	case p.tok.kind == tagIdent:
+		key := p.tok.text
+		p.next()
+		if p.tok.kind != tagOp || p.tok.text != "==" && p.tok.text != "!=" {
+			return tagSet{key}, p.err
+		}
+		negate := p.tok.text == "!="
+		p.next()
+		if p.err != nil {
+			return nil, p.err
+		}
+		if p.tok.kind != tagIdent && p.tok.kind != tagString {
+			return nil, p.errorf("expected a value instead of %s", p.tok)
+		}
+		value := p.tok.text
+		p.next()
+		return tagEqual{key: key, value: value, negate: negate}, p.err
+	}
+	return nil, p.errorf("unexpected %s", p.tok)
+}
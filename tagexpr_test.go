@@ -0,0 +1,55 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package loadbalance_test
+
+import (
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+)
+
+func TestTagExpr(t *testing.T) {
+	ins := discovery.NewInstance("tcp", "127.0.0.1:8880", 10, map[string]string{
+		"color": "blue",
+		"zone":  "us-east-1a",
+		"empty": "",
+	})
+	for expr, expected := range map[string]bool{
+		`color == "blue"`:                        true,
+		`color == blue`:                          true,
+		`color != blue`:                          false,
+		`color == "green" || zone == us-east-1a`: true,
+		`color == blue && zone == "b"`:           false,
+		`!(color == blue && zone == "b")`:        true,
+		`color`:                                  true,
+		`empty`:                                  false,
+		`missing`:                                false,
+		`missing == ""`:                          true,
+		`!missing && color == "bl\"ue" || color == blue`: true,
+	} {
+		x, err := loadbalanceEx.ParseTagExpr(expr)
+		assert.Nil(t, err)
+		assert.DeepEqual(t, expected, x.Match(ins))
+		assert.DeepEqual(t, expr, x.String())
+	}
+
+	for _, expr := range []string{``, `color ==`, `(color`, `color == "blue`, `color = blue`, `color blue`, `&& color`} {
+		_, err := loadbalanceEx.ParseTagExpr(expr)
+		assert.NotNil(t, err)
+	}
+}
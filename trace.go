@@ -0,0 +1,109 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance
+
+import (
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+)
+
+// PickTrace records the decision taken by a sampled pick.
+type PickTrace struct {
+	Time     time.Time `json:"time"`
+	Balancer string    `json:"balancer"`
+	CacheKey string    `json:"cache_key"`
+	// Candidates are the instances of the discovery result given to the balancer.
+	Candidates []InstanceState `json:"candidates"`
+	// State is the state the balancer held for the cache key right after the pick,
+	// nil if the balancer does not report its state.
+	State *State `json:"state,omitempty"`
+	// Chosen is the address of the picked instance, empty if no instance was picked.
+	Chosen string `json:"chosen"`
+}
+
+// PickTracer is implemented by balancers recording the traces of sampled picks.
+type PickTracer interface {
+	// Traces returns the retained traces, oldest first.
+	Traces() []PickTrace
+}
+
+// Traces returns the pick traces recorded by lb, or by the balancer it wraps.
+// It returns nil if no balancer in the chain implements PickTracer.
+func Traces(lb loadbalance.Loadbalancer) []PickTrace {
+	pt, ok := find(lb, func(lb loadbalance.Loadbalancer) bool {
+		_, ok := lb.(PickTracer)
+		return ok
+	}).(PickTracer)
+	if !ok {
+		return nil
+	}
+	return pt.Traces()
+}
+
+// WithPickTracing makes the Notifier record the trace of one pick out of rate,
+// retaining the last size traces, see Traces.
+func WithPickTracing(rate uint32, size int) NotifierOption {
+	return func(n *Notifier) {
+		n.traceRate = rate
+		n.traceSize = size
+	}
+}
+
+// Traces implements the PickTracer interface.
+func (n *Notifier) Traces() []PickTrace {
+	n.tracesMu.Lock()
+	defer n.tracesMu.Unlock()
+	return append([]PickTrace(nil), n.traces...)
+}
+
+func (n *Notifier) trace(e discovery.Result, ins discovery.Instance) {
+	t := PickTrace{
+		Time:       time.Now(),
+		Balancer:   n.lb.Name(),
+		CacheKey:   e.CacheKey,
+		Candidates: make([]InstanceState, 0, len(e.Instances)),
+	}
+	for _, candidate := range e.Instances {
+		addr, ok := validAddress(candidate)
+		if !ok {
+			continue
+		}
+		t.Candidates = append(t.Candidates, InstanceState{
+			Address: addr,
+			Weight:  candidate.Weight(),
+		})
+	}
+	if state, ok := Snapshot(n.lb, e.CacheKey); ok {
+		t.State = &state
+	}
+	if ins != nil && ins.Address() != nil {
+		t.Chosen = ins.Address().String()
+	}
+
+	n.tracesMu.Lock()
+	defer n.tracesMu.Unlock()
+	if n.traceSize <= 0 {
+		return
+	}
+	if len(n.traces) == n.traceSize {
+		copy(n.traces, n.traces[1:])
+		n.traces = n.traces[:len(n.traces)-1]
+	}
+	n.traces = append(n.traces, t)
+}
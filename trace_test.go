@@ -0,0 +1,58 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance_test
+
+import (
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app/client/discovery"
+	"github.com/cloudwego/hertz/pkg/app/client/loadbalance"
+	"github.com/cloudwego/hertz/pkg/common/test/assert"
+	loadbalanceEx "github.com/hertz-contrib/loadbalance"
+	roundrobin "github.com/hertz-contrib/loadbalance/round_robin"
+)
+
+func TestPickTracing(t *testing.T) {
+	n := loadbalanceEx.NewNotifier(roundrobin.NewRoundRobinBalancer(), loadbalanceEx.WithPickTracing(3, 2))
+	lb := wrapped{n}
+	e := discovery.Result{
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "127.0.0.1:8880", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8881", 20, nil),
+		},
+		CacheKey: "a",
+	}
+	assert.DeepEqual(t, 0, len(loadbalanceEx.Traces(lb)))
+
+	for i := 0; i < 9; i++ {
+		lb.Pick(e)
+	}
+	traces := loadbalanceEx.Traces(lb)
+	assert.DeepEqual(t, 2, len(traces))
+	// picks 6 and 9
+	assert.DeepEqual(t, "127.0.0.1:8881", traces[0].Chosen)
+	assert.DeepEqual(t, "127.0.0.1:8880", traces[1].Chosen)
+	assert.DeepEqual(t, "a", traces[1].CacheKey)
+	assert.DeepEqual(t, "round_robin", traces[1].Balancer)
+	assert.DeepEqual(t, []loadbalanceEx.InstanceState{
+		{Address: "127.0.0.1:8880", Weight: 10},
+		{Address: "127.0.0.1:8881", Weight: 20},
+	}, traces[1].Candidates)
+	assert.DeepEqual(t, uint64(5), traces[1].State.Instances[0].Picks)
+
+	assert.Nil(t, loadbalanceEx.Traces(loadbalance.NewWeightedBalancer()))
+}
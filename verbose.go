@@ -0,0 +1,36 @@
+/*
+ * Copyright 2022 CloudWeGo Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance
+
+import "sync/atomic"
+
+var verbose uint32
+
+// SetVerbose enables or disables the logging of every pick by the balancers of this module.
+// It can be switched on a live process, the picks are logged with hlog at the debug level.
+func SetVerbose(enabled bool) {
+	var v uint32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreUint32(&verbose, v)
+}
+
+// Verbose reports whether the logging of every pick is enabled.
+func Verbose() bool {
+	return atomic.LoadUint32(&verbose) == 1
+}